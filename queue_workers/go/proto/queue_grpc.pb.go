@@ -19,8 +19,24 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Queue_AddTask_FullMethodName    = "/queue.Queue/AddTask"
-	Queue_WatchQueue_FullMethodName = "/queue.Queue/WatchQueue"
+	Queue_AddTask_FullMethodName           = "/queue.Queue/AddTask"
+	Queue_AddTasks_FullMethodName          = "/queue.Queue/AddTasks"
+	Queue_PopTasks_FullMethodName          = "/queue.Queue/PopTasks"
+	Queue_PeekTasks_FullMethodName         = "/queue.Queue/PeekTasks"
+	Queue_RemoveTask_FullMethodName        = "/queue.Queue/RemoveTask"
+	Queue_WatchQueue_FullMethodName        = "/queue.Queue/WatchQueue"
+	Queue_LeaseTask_FullMethodName         = "/queue.Queue/LeaseTask"
+	Queue_AckTask_FullMethodName           = "/queue.Queue/AckTask"
+	Queue_NackTask_FullMethodName          = "/queue.Queue/NackTask"
+	Queue_GetTask_FullMethodName           = "/queue.Queue/GetTask"
+	Queue_ListDeadLetters_FullMethodName   = "/queue.Queue/ListDeadLetters"
+	Queue_GetDeadLetter_FullMethodName     = "/queue.Queue/GetDeadLetter"
+	Queue_ReplayDeadLetters_FullMethodName = "/queue.Queue/ReplayDeadLetters"
+	Queue_CreateQueue_FullMethodName       = "/queue.Queue/CreateQueue"
+	Queue_DeleteQueue_FullMethodName       = "/queue.Queue/DeleteQueue"
+	Queue_ListQueues_FullMethodName        = "/queue.Queue/ListQueues"
+	Queue_GetQueueStats_FullMethodName     = "/queue.Queue/GetQueueStats"
+	Queue_Drain_FullMethodName             = "/queue.Queue/Drain"
 )
 
 // QueueClient is the client API for Queue service.
@@ -28,7 +44,40 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type QueueClient interface {
 	AddTask(ctx context.Context, in *TaskAddRequest, opts ...grpc.CallOption) (*TaskAddReply, error)
+	// AddTasks adds a batch of tasks, possibly to different queues, reporting the outcome of each task
+	AddTasks(ctx context.Context, in *TasksAddRequest, opts ...grpc.CallOption) (*TasksAddReply, error)
+	// PopTasks removes up to max_n tasks from the head of a queue in one go
+	PopTasks(ctx context.Context, in *TasksPopRequest, opts ...grpc.CallOption) (*TasksPopReply, error)
+	// PeekTasks lists up to max_n tasks from the head of a queue, in the order they would be handed out, leaving them queued
+	PeekTasks(ctx context.Context, in *TasksPeekRequest, opts ...grpc.CallOption) (*TasksPeekReply, error)
+	// RemoveTask drops a task whether it is queued, delayed or leased
+	RemoveTask(ctx context.Context, in *TaskRemoveRequest, opts ...grpc.CallOption) (*TaskRemoveReply, error)
+	// WatchQueue streams every task enqueued after the watch started, including redeliveries
 	WatchQueue(ctx context.Context, in *TaskWatchRequest, opts ...grpc.CallOption) (Queue_WatchQueueClient, error)
+	// LeaseTask hands out the next task, hiding it from other workers until it is acked or the lease expires
+	LeaseTask(ctx context.Context, in *TaskLeaseRequest, opts ...grpc.CallOption) (*TaskLeaseReply, error)
+	// AckTask marks a leased task as done and removes it from the queue
+	AckTask(ctx context.Context, in *TaskAckRequest, opts ...grpc.CallOption) (*TaskAckReply, error)
+	// NackTask gives up a lease, the task is redelivered right away
+	NackTask(ctx context.Context, in *TaskNackRequest, opts ...grpc.CallOption) (*TaskNackReply, error)
+	// GetTask looks up where a task is in its lifecycle, tasks that succeeded or died are kept
+	// for the result_ttl of their queue
+	GetTask(ctx context.Context, in *TaskGetRequest, opts ...grpc.CallOption) (*TaskGetReply, error)
+	// ListDeadLetters lists the tasks that ran out of delivery attempts in a queue
+	ListDeadLetters(ctx context.Context, in *DeadLetterListRequest, opts ...grpc.CallOption) (*DeadLetterListReply, error)
+	// GetDeadLetter inspects a single dead lettered task
+	GetDeadLetter(ctx context.Context, in *DeadLetterGetRequest, opts ...grpc.CallOption) (*DeadLetterGetReply, error)
+	// ReplayDeadLetters moves dead lettered tasks back into their queue with their attempts reset
+	ReplayDeadLetters(ctx context.Context, in *DeadLetterReplayRequest, opts ...grpc.CallOption) (*DeadLetterReplayReply, error)
+	// CreateQueue creates a queue, along with its dead letter queue when max_attempts is set
+	CreateQueue(ctx context.Context, in *QueueCreateRequest, opts ...grpc.CallOption) (*QueueCreateReply, error)
+	// DeleteQueue deletes a queue with every task in it, along with its dead letter queue
+	DeleteQueue(ctx context.Context, in *QueueDeleteRequest, opts ...grpc.CallOption) (*QueueDeleteReply, error)
+	ListQueues(ctx context.Context, in *QueueListRequest, opts ...grpc.CallOption) (*QueueListReply, error)
+	GetQueueStats(ctx context.Context, in *QueueStatsRequest, opts ...grpc.CallOption) (*QueueStatsReply, error)
+	// Drain readies the server for a restart, adds fail with UNAVAILABLE and watches end so clients
+	// move to another server, leased tasks can still be acked. The reply tells what is left to finish.
+	Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error)
 }
 
 type queueClient struct {
@@ -48,6 +97,42 @@ func (c *queueClient) AddTask(ctx context.Context, in *TaskAddRequest, opts ...g
 	return out, nil
 }
 
+func (c *queueClient) AddTasks(ctx context.Context, in *TasksAddRequest, opts ...grpc.CallOption) (*TasksAddReply, error) {
+	out := new(TasksAddReply)
+	err := c.cc.Invoke(ctx, Queue_AddTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) PopTasks(ctx context.Context, in *TasksPopRequest, opts ...grpc.CallOption) (*TasksPopReply, error) {
+	out := new(TasksPopReply)
+	err := c.cc.Invoke(ctx, Queue_PopTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) PeekTasks(ctx context.Context, in *TasksPeekRequest, opts ...grpc.CallOption) (*TasksPeekReply, error) {
+	out := new(TasksPeekReply)
+	err := c.cc.Invoke(ctx, Queue_PeekTasks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) RemoveTask(ctx context.Context, in *TaskRemoveRequest, opts ...grpc.CallOption) (*TaskRemoveReply, error) {
+	out := new(TaskRemoveReply)
+	err := c.cc.Invoke(ctx, Queue_RemoveTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *queueClient) WatchQueue(ctx context.Context, in *TaskWatchRequest, opts ...grpc.CallOption) (Queue_WatchQueueClient, error) {
 	stream, err := c.cc.NewStream(ctx, &Queue_ServiceDesc.Streams[0], Queue_WatchQueue_FullMethodName, opts...)
 	if err != nil {
@@ -80,12 +165,153 @@ func (x *queueWatchQueueClient) Recv() (*TaskWatchReply, error) {
 	return m, nil
 }
 
+func (c *queueClient) LeaseTask(ctx context.Context, in *TaskLeaseRequest, opts ...grpc.CallOption) (*TaskLeaseReply, error) {
+	out := new(TaskLeaseReply)
+	err := c.cc.Invoke(ctx, Queue_LeaseTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) AckTask(ctx context.Context, in *TaskAckRequest, opts ...grpc.CallOption) (*TaskAckReply, error) {
+	out := new(TaskAckReply)
+	err := c.cc.Invoke(ctx, Queue_AckTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) NackTask(ctx context.Context, in *TaskNackRequest, opts ...grpc.CallOption) (*TaskNackReply, error) {
+	out := new(TaskNackReply)
+	err := c.cc.Invoke(ctx, Queue_NackTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) GetTask(ctx context.Context, in *TaskGetRequest, opts ...grpc.CallOption) (*TaskGetReply, error) {
+	out := new(TaskGetReply)
+	err := c.cc.Invoke(ctx, Queue_GetTask_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) ListDeadLetters(ctx context.Context, in *DeadLetterListRequest, opts ...grpc.CallOption) (*DeadLetterListReply, error) {
+	out := new(DeadLetterListReply)
+	err := c.cc.Invoke(ctx, Queue_ListDeadLetters_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) GetDeadLetter(ctx context.Context, in *DeadLetterGetRequest, opts ...grpc.CallOption) (*DeadLetterGetReply, error) {
+	out := new(DeadLetterGetReply)
+	err := c.cc.Invoke(ctx, Queue_GetDeadLetter_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) ReplayDeadLetters(ctx context.Context, in *DeadLetterReplayRequest, opts ...grpc.CallOption) (*DeadLetterReplayReply, error) {
+	out := new(DeadLetterReplayReply)
+	err := c.cc.Invoke(ctx, Queue_ReplayDeadLetters_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) CreateQueue(ctx context.Context, in *QueueCreateRequest, opts ...grpc.CallOption) (*QueueCreateReply, error) {
+	out := new(QueueCreateReply)
+	err := c.cc.Invoke(ctx, Queue_CreateQueue_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) DeleteQueue(ctx context.Context, in *QueueDeleteRequest, opts ...grpc.CallOption) (*QueueDeleteReply, error) {
+	out := new(QueueDeleteReply)
+	err := c.cc.Invoke(ctx, Queue_DeleteQueue_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) ListQueues(ctx context.Context, in *QueueListRequest, opts ...grpc.CallOption) (*QueueListReply, error) {
+	out := new(QueueListReply)
+	err := c.cc.Invoke(ctx, Queue_ListQueues_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) GetQueueStats(ctx context.Context, in *QueueStatsRequest, opts ...grpc.CallOption) (*QueueStatsReply, error) {
+	out := new(QueueStatsReply)
+	err := c.cc.Invoke(ctx, Queue_GetQueueStats_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *queueClient) Drain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*DrainReply, error) {
+	out := new(DrainReply)
+	err := c.cc.Invoke(ctx, Queue_Drain_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // QueueServer is the server API for Queue service.
 // All implementations must embed UnimplementedQueueServer
 // for forward compatibility
 type QueueServer interface {
 	AddTask(context.Context, *TaskAddRequest) (*TaskAddReply, error)
+	// AddTasks adds a batch of tasks, possibly to different queues, reporting the outcome of each task
+	AddTasks(context.Context, *TasksAddRequest) (*TasksAddReply, error)
+	// PopTasks removes up to max_n tasks from the head of a queue in one go
+	PopTasks(context.Context, *TasksPopRequest) (*TasksPopReply, error)
+	// PeekTasks lists up to max_n tasks from the head of a queue, in the order they would be handed out, leaving them queued
+	PeekTasks(context.Context, *TasksPeekRequest) (*TasksPeekReply, error)
+	// RemoveTask drops a task whether it is queued, delayed or leased
+	RemoveTask(context.Context, *TaskRemoveRequest) (*TaskRemoveReply, error)
+	// WatchQueue streams every task enqueued after the watch started, including redeliveries
 	WatchQueue(*TaskWatchRequest, Queue_WatchQueueServer) error
+	// LeaseTask hands out the next task, hiding it from other workers until it is acked or the lease expires
+	LeaseTask(context.Context, *TaskLeaseRequest) (*TaskLeaseReply, error)
+	// AckTask marks a leased task as done and removes it from the queue
+	AckTask(context.Context, *TaskAckRequest) (*TaskAckReply, error)
+	// NackTask gives up a lease, the task is redelivered right away
+	NackTask(context.Context, *TaskNackRequest) (*TaskNackReply, error)
+	// GetTask looks up where a task is in its lifecycle, tasks that succeeded or died are kept
+	// for the result_ttl of their queue
+	GetTask(context.Context, *TaskGetRequest) (*TaskGetReply, error)
+	// ListDeadLetters lists the tasks that ran out of delivery attempts in a queue
+	ListDeadLetters(context.Context, *DeadLetterListRequest) (*DeadLetterListReply, error)
+	// GetDeadLetter inspects a single dead lettered task
+	GetDeadLetter(context.Context, *DeadLetterGetRequest) (*DeadLetterGetReply, error)
+	// ReplayDeadLetters moves dead lettered tasks back into their queue with their attempts reset
+	ReplayDeadLetters(context.Context, *DeadLetterReplayRequest) (*DeadLetterReplayReply, error)
+	// CreateQueue creates a queue, along with its dead letter queue when max_attempts is set
+	CreateQueue(context.Context, *QueueCreateRequest) (*QueueCreateReply, error)
+	// DeleteQueue deletes a queue with every task in it, along with its dead letter queue
+	DeleteQueue(context.Context, *QueueDeleteRequest) (*QueueDeleteReply, error)
+	ListQueues(context.Context, *QueueListRequest) (*QueueListReply, error)
+	GetQueueStats(context.Context, *QueueStatsRequest) (*QueueStatsReply, error)
+	// Drain readies the server for a restart, adds fail with UNAVAILABLE and watches end so clients
+	// move to another server, leased tasks can still be acked. The reply tells what is left to finish.
+	Drain(context.Context, *DrainRequest) (*DrainReply, error)
 	mustEmbedUnimplementedQueueServer()
 }
 
@@ -96,9 +322,57 @@ type UnimplementedQueueServer struct {
 func (UnimplementedQueueServer) AddTask(context.Context, *TaskAddRequest) (*TaskAddReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method AddTask not implemented")
 }
+func (UnimplementedQueueServer) AddTasks(context.Context, *TasksAddRequest) (*TasksAddReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddTasks not implemented")
+}
+func (UnimplementedQueueServer) PopTasks(context.Context, *TasksPopRequest) (*TasksPopReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PopTasks not implemented")
+}
+func (UnimplementedQueueServer) PeekTasks(context.Context, *TasksPeekRequest) (*TasksPeekReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PeekTasks not implemented")
+}
+func (UnimplementedQueueServer) RemoveTask(context.Context, *TaskRemoveRequest) (*TaskRemoveReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveTask not implemented")
+}
 func (UnimplementedQueueServer) WatchQueue(*TaskWatchRequest, Queue_WatchQueueServer) error {
 	return status.Errorf(codes.Unimplemented, "method WatchQueue not implemented")
 }
+func (UnimplementedQueueServer) LeaseTask(context.Context, *TaskLeaseRequest) (*TaskLeaseReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaseTask not implemented")
+}
+func (UnimplementedQueueServer) AckTask(context.Context, *TaskAckRequest) (*TaskAckReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AckTask not implemented")
+}
+func (UnimplementedQueueServer) NackTask(context.Context, *TaskNackRequest) (*TaskNackReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NackTask not implemented")
+}
+func (UnimplementedQueueServer) GetTask(context.Context, *TaskGetRequest) (*TaskGetReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTask not implemented")
+}
+func (UnimplementedQueueServer) ListDeadLetters(context.Context, *DeadLetterListRequest) (*DeadLetterListReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadLetters not implemented")
+}
+func (UnimplementedQueueServer) GetDeadLetter(context.Context, *DeadLetterGetRequest) (*DeadLetterGetReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeadLetter not implemented")
+}
+func (UnimplementedQueueServer) ReplayDeadLetters(context.Context, *DeadLetterReplayRequest) (*DeadLetterReplayReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReplayDeadLetters not implemented")
+}
+func (UnimplementedQueueServer) CreateQueue(context.Context, *QueueCreateRequest) (*QueueCreateReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateQueue not implemented")
+}
+func (UnimplementedQueueServer) DeleteQueue(context.Context, *QueueDeleteRequest) (*QueueDeleteReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteQueue not implemented")
+}
+func (UnimplementedQueueServer) ListQueues(context.Context, *QueueListRequest) (*QueueListReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListQueues not implemented")
+}
+func (UnimplementedQueueServer) GetQueueStats(context.Context, *QueueStatsRequest) (*QueueStatsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQueueStats not implemented")
+}
+func (UnimplementedQueueServer) Drain(context.Context, *DrainRequest) (*DrainReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Drain not implemented")
+}
 func (UnimplementedQueueServer) mustEmbedUnimplementedQueueServer() {}
 
 // UnsafeQueueServer may be embedded to opt out of forward compatibility for this service.
@@ -130,6 +404,78 @@ func _Queue_AddTask_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Queue_AddTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TasksAddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).AddTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_AddTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).AddTasks(ctx, req.(*TasksAddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_PopTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TasksPopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).PopTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_PopTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).PopTasks(ctx, req.(*TasksPopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_PeekTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TasksPeekRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).PeekTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_PeekTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).PeekTasks(ctx, req.(*TasksPeekRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_RemoveTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskRemoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).RemoveTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_RemoveTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).RemoveTask(ctx, req.(*TaskRemoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Queue_WatchQueue_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(TaskWatchRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -151,6 +497,222 @@ func (x *queueWatchQueueServer) Send(m *TaskWatchReply) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Queue_LeaseTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).LeaseTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_LeaseTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).LeaseTask(ctx, req.(*TaskLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_AckTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskAckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).AckTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_AckTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).AckTask(ctx, req.(*TaskAckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_NackTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskNackRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).NackTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_NackTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).NackTask(ctx, req.(*TaskNackRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_GetTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TaskGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).GetTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_GetTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).GetTask(ctx, req.(*TaskGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_ListDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).ListDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_ListDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).ListDeadLetters(ctx, req.(*DeadLetterListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_GetDeadLetter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterGetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).GetDeadLetter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_GetDeadLetter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).GetDeadLetter(ctx, req.(*DeadLetterGetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_ReplayDeadLetters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeadLetterReplayRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).ReplayDeadLetters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_ReplayDeadLetters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).ReplayDeadLetters(ctx, req.(*DeadLetterReplayRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_CreateQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueCreateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).CreateQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_CreateQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).CreateQueue(ctx, req.(*QueueCreateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_DeleteQueue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueDeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).DeleteQueue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_DeleteQueue_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).DeleteQueue(ctx, req.(*QueueDeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_ListQueues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).ListQueues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_ListQueues_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).ListQueues(ctx, req.(*QueueListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_GetQueueStats_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueueStatsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).GetQueueStats(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_GetQueueStats_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).GetQueueStats(ctx, req.(*QueueStatsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Queue_Drain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(QueueServer).Drain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Queue_Drain_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(QueueServer).Drain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Queue_ServiceDesc is the grpc.ServiceDesc for Queue service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -162,6 +724,70 @@ var Queue_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "AddTask",
 			Handler:    _Queue_AddTask_Handler,
 		},
+		{
+			MethodName: "AddTasks",
+			Handler:    _Queue_AddTasks_Handler,
+		},
+		{
+			MethodName: "PopTasks",
+			Handler:    _Queue_PopTasks_Handler,
+		},
+		{
+			MethodName: "PeekTasks",
+			Handler:    _Queue_PeekTasks_Handler,
+		},
+		{
+			MethodName: "RemoveTask",
+			Handler:    _Queue_RemoveTask_Handler,
+		},
+		{
+			MethodName: "LeaseTask",
+			Handler:    _Queue_LeaseTask_Handler,
+		},
+		{
+			MethodName: "AckTask",
+			Handler:    _Queue_AckTask_Handler,
+		},
+		{
+			MethodName: "NackTask",
+			Handler:    _Queue_NackTask_Handler,
+		},
+		{
+			MethodName: "GetTask",
+			Handler:    _Queue_GetTask_Handler,
+		},
+		{
+			MethodName: "ListDeadLetters",
+			Handler:    _Queue_ListDeadLetters_Handler,
+		},
+		{
+			MethodName: "GetDeadLetter",
+			Handler:    _Queue_GetDeadLetter_Handler,
+		},
+		{
+			MethodName: "ReplayDeadLetters",
+			Handler:    _Queue_ReplayDeadLetters_Handler,
+		},
+		{
+			MethodName: "CreateQueue",
+			Handler:    _Queue_CreateQueue_Handler,
+		},
+		{
+			MethodName: "DeleteQueue",
+			Handler:    _Queue_DeleteQueue_Handler,
+		},
+		{
+			MethodName: "ListQueues",
+			Handler:    _Queue_ListQueues_Handler,
+		},
+		{
+			MethodName: "GetQueueStats",
+			Handler:    _Queue_GetQueueStats_Handler,
+		},
+		{
+			MethodName: "Drain",
+			Handler:    _Queue_Drain_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
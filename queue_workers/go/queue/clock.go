@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"sync"
+	"time"
+)
+
+// clock tells a queue the time and runs its lease and delay timers
+type clock interface {
+	now() time.Time
+	afterFunc(d time.Duration, f func()) stopper
+}
+
+type stopper interface {
+	Stop() bool
+}
+
+// wallClock is the clock of every queue but replicas
+type wallClock struct{}
+
+func (wallClock) now() time.Time {
+	return time.Now()
+}
+
+func (wallClock) afterFunc(d time.Duration, f func()) stopper {
+	return time.AfterFunc(d, f)
+}
+
+// logClock is the clock of replicas, it only moves when a change from the log is applied
+// so every replica sees the same time at the same point of the log. Timers falling due fire
+// in order while it is advanced, with the clock set to when they were due.
+type logClock struct {
+	mux    sync.Mutex
+	t      time.Time
+	seq    uint64
+	timers map[*logTimer]struct{}
+}
+
+type logTimer struct {
+	c   *logClock
+	at  time.Time
+	seq uint64
+	f   func()
+}
+
+func newLogClock() *logClock {
+	return &logClock{timers: map[*logTimer]struct{}{}}
+}
+
+func (c *logClock) now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.t
+}
+
+func (c *logClock) afterFunc(d time.Duration, f func()) stopper {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.seq++
+	t := &logTimer{c: c, at: c.t.Add(d), seq: c.seq, f: f}
+	c.timers[t] = struct{}{}
+	return t
+}
+
+func (t *logTimer) Stop() bool {
+	t.c.mux.Lock()
+	defer t.c.mux.Unlock()
+	_, ok := t.c.timers[t]
+	delete(t.c.timers, t)
+	return ok
+}
+
+// first returns the timer due first, ties broken by when they were armed, it is called with the lock held
+func (c *logClock) first() *logTimer {
+	var first *logTimer
+	for t := range c.timers {
+		if first == nil || t.at.Before(first.at) || t.at.Equal(first.at) && t.seq < first.seq {
+			first = t
+		}
+	}
+	return first
+}
+
+// advance moves the clock forward to t, firing the timers due by then, it never goes back in time.
+// The timers run without the clock locked, they are free to arm new ones.
+func (c *logClock) advance(t time.Time) {
+	for {
+		c.mux.Lock()
+		next := c.first()
+		if next == nil || next.at.After(t) {
+			if t.After(c.t) {
+				c.t = t
+			}
+			c.mux.Unlock()
+			return
+		}
+		delete(c.timers, next)
+		if next.at.After(c.t) {
+			c.t = next.at
+		}
+		c.mux.Unlock()
+		next.f()
+	}
+}
+
+// next returns when the first timer falls due, false if none is armed
+func (c *logClock) next() (time.Time, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if t := c.first(); t != nil {
+		return t.at, true
+	}
+	return time.Time{}, false
+}
+
+// set puts the clock at t, back in time too, when the replica is restored from a snapshot
+func (c *logClock) set(t time.Time) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.t = t
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DeadLetterName is the name the server registers the dead letter queue of a queue under
+func DeadLetterName(name string) string {
+	return name + ".dlq"
+}
+
+// Replay moves dead lettered items back into their source queue with their attempts reset,
+// every item is moved when no keys are given. Keys that are unknown to dlq or already present
+// in src again are left where they are. Items leave dlq before they are added to src so none
+// is delivered twice, those src refuses are put back in dlq. Replay stops at the first item that
+// fails to move and returns the keys moved so far.
+func Replay[K comparable, V any](dlq, src Queue[K, V], keys ...K) ([]K, error) {
+	if len(keys) == 0 {
+		for _, v := range dlq.List() {
+			keys = append(keys, v.key)
+		}
+	}
+	replayed := []K{}
+	for _, k := range keys {
+		if src.Get(k) != nil {
+			continue
+		}
+		v, err := dlq.Remove(k)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return replayed, fmt.Errorf("replaying %v: %w", k, err)
+		}
+		if err := src.Add(k, v.content, WithPriority(v.priority)); err != nil {
+			if perr := dlq.Add(k, v.content, WithPriority(v.priority), WithAttempts(v.attempts)); perr != nil {
+				return replayed, fmt.Errorf("replaying %v, putting it back in the dead letter queue failed too: %w", k, errors.Join(err, perr))
+			}
+			if errors.Is(err, ErrDuplicateKey) {
+				// added to src since it was looked up
+				continue
+			}
+			return replayed, fmt.Errorf("replaying %v: %w", k, err)
+		}
+		replayed = append(replayed, k)
+	}
+	return replayed, nil
+}
@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeadLetter(t *testing.T) {
+	dlq := NewQueue[string, any]()
+	q := NewQueue[string, any](WithDeadLetter(dlq, 2))
+	q.Add("poison", "pill", WithPriority(3))
+	q.Add("fine", "task")
+
+	l := mustLease(t, q, time.Minute)
+	assert.Equal(t, l.Item().Attempts(), 1)
+	assert.NoError(t, q.Nack(l.ID()))
+	assert.NoError(t, q.Ack(mustLease(t, q, time.Minute).ID()))
+
+	// second failure uses up the attempts, this time through lease expiry
+	l = mustLease(t, q, 10*time.Millisecond)
+	assert.Equal(t, l.Item().Attempts(), 2)
+	assert.Eventually(t, func() bool { return dlq.Get("poison") != nil }, time.Second, 5*time.Millisecond)
+	assert.Nil(t, q.Get("poison"))
+	assert.Nil(t, q.Peep())
+
+	dead := dlq.List()
+	assert.Len(t, dead, 1)
+	assert.Equal(t, dead[0].Attempts(), 2)
+	assert.Equal(t, dead[0].Priority(), 3)
+
+	replayed, err := Replay(dlq, q)
+	assert.NoError(t, err)
+	assert.Equal(t, replayed, []string{"poison"})
+	assert.Empty(t, dlq.List())
+	v := q.Get("poison")
+	assert.Equal(t, v.Attempts(), 0)
+	assert.Equal(t, v.Priority(), 3)
+}
+
+func TestReplaySkipsPresentKeys(t *testing.T) {
+	dlq := NewQueue[string, any]()
+	q := NewQueue[string, any](WithDeadLetter(dlq, 1))
+	dlq.Add("foo", "old")
+	dlq.Add("bar", "old")
+	q.Add("foo", "new")
+
+	replayed, err := Replay(dlq, q, "foo", "bar", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, replayed, []string{"bar"})
+	_, v := q.Get("foo").KeyValue()
+	assert.Equal(t, v, "new")
+	assert.NotNil(t, dlq.Get("foo"))
+}
+
+func TestReplayKeepsRejectedItems(t *testing.T) {
+	dlq := NewQueue[string, any]()
+	q := NewQueue[string, any](WithMaxSize(1))
+	dlq.Add("foo", "a")
+	dlq.Add("bar", "b")
+
+	replayed, err := Replay(dlq, q)
+	assert.ErrorIs(t, err, ErrQueueFull)
+	assert.Equal(t, replayed, []string{"foo"})
+	assert.Nil(t, dlq.Get("foo"))
+	assert.NotNil(t, q.Get("foo"))
+	// bar left dlq before q refused it and was put back
+	assert.NotNil(t, dlq.Get("bar"))
+	assert.Nil(t, q.Get("bar"))
+}
+
+func TestDeadLetterRefused(t *testing.T) {
+	dlq := NewQueue[string, any](WithMaxSize(1))
+	dlq.Add("other", "task")
+	q := NewQueue[string, any](WithDeadLetter(dlq, 1), WithMaxSize(1))
+	q.Add("poison", "pill")
+
+	// a full dead letter queue leaves the item in the queue, still taking its room
+	assert.ErrorIs(t, q.Nack(mustLease(t, q, time.Minute).ID()), ErrQueueFull)
+	assert.Equal(t, q.Get("poison").Attempts(), 1)
+	assert.ErrorIs(t, q.Add("late", "task"), ErrQueueFull)
+
+	dlq.Remove("other")
+	assert.NoError(t, q.Nack(mustLease(t, q, time.Minute).ID()))
+	assert.Nil(t, q.Get("poison"))
+	assert.Equal(t, dlq.Get("poison").Attempts(), 2)
+	assert.Equal(t, q.Stats().DeadLettered, 1)
+}
@@ -0,0 +1,36 @@
+package queue
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrAlreadyDone is returned by Add on a queue with a dedup window for a key
+// that was popped or acked within the window
+var ErrAlreadyDone = errors.New("key was done within the dedup window")
+
+// WithDedupWindow remembers the keys of items that were popped or acked for d, adding them again
+// fails with ErrAlreadyDone so retrying producers do not cause double processing. Items removed,
+// expired, dropped on overflow or dead lettered were not processed and are not remembered,
+// so dead lettered items can be replayed.
+func WithDedupWindow(d time.Duration) Option {
+	return func(c *config) {
+		c.dedupWindow = d
+	}
+}
+
+// finish drops an item that was handed out for good, remembering its key on queues with a dedup window
+func (q *queue[K, V]) finish(v *Item[K, V]) {
+	if q.dedupWindow <= 0 {
+		q.drop(v)
+		return
+	}
+	q.done.put(v.key, struct{}{}, q.now())
+	q.leave(opDone, EventRemoved, v)
+}
+
+// doneAt reports when k was done if that was within the dedup window
+func (q *queue[K, V]) doneAt(k K) (time.Time, bool) {
+	_, at, ok := q.done.get(k, q.now())
+	return at, ok
+}
@@ -0,0 +1,65 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupWindow(t *testing.T) {
+	q := NewQueue[string, any](WithDedupWindow(50 * time.Millisecond))
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Add("fizz", "bazz")
+	q.Pop()
+	require.NoError(t, q.Ack(mustLease(t, q, time.Minute).ID()))
+	_, err := q.Remove("fizz")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, q.Add("foo", "bar"), ErrAlreadyDone)
+	assert.ErrorIs(t, q.Add("john", "doe"), ErrAlreadyDone)
+	// removed items were never processed
+	assert.NoError(t, q.Add("fizz", "bazz"))
+
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, q.Add("foo", "bar"))
+	assert.NoError(t, q.Add("john", "doe"))
+}
+
+func TestDedupWindowDeadLetter(t *testing.T) {
+	dlq := NewQueue[string, any]()
+	q := NewQueue[string, any](WithDeadLetter(dlq, 1), WithDedupWindow(time.Minute))
+	q.Add("poison", "pill")
+	require.NoError(t, q.Nack(mustLease(t, q, time.Minute).ID()))
+	assert.NotNil(t, dlq.Get("poison"))
+	replayed, err := Replay(dlq, q)
+	assert.NoError(t, err)
+	assert.Equal(t, replayed, []string{"poison"})
+}
+
+func TestDurableDedupWindow(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{}, WithDedupWindow(time.Minute))
+	require.NoError(t, err)
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Pop()
+	// simulate a crash so the done key is only in the log
+	q.(*queue[string, any]).journal.stop()
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{}, WithDedupWindow(time.Minute))
+	require.NoError(t, err)
+	assert.ErrorIs(t, q.Add("foo", "bar"), ErrAlreadyDone)
+	q.Pop()
+	// the snapshot written on close carries the done keys over
+	require.NoError(t, q.Close())
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{}, WithDedupWindow(time.Minute))
+	require.NoError(t, err)
+	defer q.Close()
+	assert.ErrorIs(t, q.Add("foo", "bar"), ErrAlreadyDone)
+	assert.ErrorIs(t, q.Add("john", "doe"), ErrAlreadyDone)
+	assert.Empty(t, keysOfQueue(q))
+}
@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// WithNotBefore holds an item back until t, it is not handed out nor sent to subscribers before then
+func WithNotBefore(t time.Time) AddOption {
+	return func(c *addConfig) {
+		c.notBefore, c.delay = t, 0
+	}
+}
+
+// WithDelay holds an item back for d from when it is added, by the clock of the queue
+func WithDelay(d time.Duration) AddOption {
+	return func(c *addConfig) {
+		c.notBefore, c.delay = time.Time{}, d
+	}
+}
+
+// NotBefore is when a delayed item becomes available, zero for items that were available right away
+func (i *Item[K, V]) NotBefore() time.Time {
+	return i.notBefore
+}
+
+// readyAt is when the item became or becomes available
+func (i *Item[K, V]) readyAt() time.Time {
+	if i.notBefore.After(i.addedAt) {
+		return i.notBefore
+	}
+	return i.addedAt
+}
+
+// dueHeap orders delayed items by when they become available
+type dueHeap[K comparable, V any] struct {
+	itemHeap[K, V]
+}
+
+func (h dueHeap[K, V]) Less(i, j int) bool {
+	if !h.itemHeap[i].notBefore.Equal(h.itemHeap[j].notBefore) {
+		return h.itemHeap[i].notBefore.Before(h.itemHeap[j].notBefore)
+	}
+	return h.itemHeap[i].seq < h.itemHeap[j].seq
+}
+
+// delay holds an item back until its not before time
+func (q *queue[K, V]) delay(v *Item[K, V]) {
+	v.delayed = true
+	heap.Push(&q.delayed, v)
+	q.schedule()
+}
+
+func (q *queue[K, V]) undelay(v *Item[K, V]) {
+	heap.Remove(&q.delayed, v.index)
+	v.delayed = false
+	q.schedule()
+}
+
+// promoteDue moves the delayed items that became available into the queue
+func (q *queue[K, V]) promoteDue() {
+	now := q.now()
+	for len(q.delayed.itemHeap) > 0 && !q.delayed.itemHeap[0].notBefore.After(now) {
+		v := heap.Pop(&q.delayed).(*Item[K, V])
+		v.delayed = false
+		q.items.push(v)
+		q.publish(EventAdded, v)
+	}
+	q.schedule()
+}
+
+// schedule arms the timer for the next delayed item to become available
+func (q *queue[K, V]) schedule() {
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	if len(q.delayed.itemHeap) == 0 || q.closed {
+		return
+	}
+	q.timer = q.clock.afterFunc(q.delayed.itemHeap[0].notBefore.Sub(q.now()), func() {
+		q.mux.Lock()
+		defer q.mux.Unlock()
+		q.promoteDue()
+	})
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelay(t *testing.T) {
+	q := NewQueue[string, any]()
+	s := q.Subscribe()
+	defer s.Close()
+	q.Add("later", "b", WithDelay(40*time.Millisecond))
+	q.Add("soon", "a", WithDelay(20*time.Millisecond))
+	q.Add("now", "c")
+
+	assert.Equal(t, next(t, s).Item.key, "now")
+	assert.Equal(t, mustLease(t, q, time.Minute).Item().key, "now")
+	assert.Nil(t, q.Peep())
+	assert.Nil(t, mustLease(t, q, time.Minute))
+	// delayed items still own their key
+	assert.NotNil(t, q.Get("soon"))
+	assert.Equal(t, q.Stats().Delayed, 2)
+
+	// subscribers hear about delayed items once they are due, in due order
+	assert.Equal(t, next(t, s).Item.key, "soon")
+	assert.Equal(t, next(t, s).Item.key, "later")
+	assert.Equal(t, q.Stats().Delayed, 0)
+	assert.Equal(t, q.Pop().key, "soon")
+	assert.Equal(t, q.Pop().key, "later")
+}
+
+func TestDelayRemove(t *testing.T) {
+	q := NewQueue[string, any]()
+	q.Add("foo", "bar", WithDelay(20*time.Millisecond))
+	q.Add("john", "doe", WithDelay(time.Hour))
+	_, err := q.Remove("foo")
+	assert.NoError(t, err)
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, q.Peep())
+	assert.Equal(t, q.Stats().Delayed, 1)
+	assert.NoError(t, q.Close())
+}
+
+func TestDelayDurable(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	notBefore := time.Now().Add(30 * time.Millisecond)
+	q.Add("foo", "bar", WithNotBefore(notBefore))
+	require.NoError(t, q.Close())
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	defer q.Close()
+	assert.Nil(t, q.Peep())
+	assert.True(t, q.Get("foo").NotBefore().Equal(notBefore))
+	assert.Eventually(t, func() bool { return q.Peep() != nil }, time.Second, 5*time.Millisecond)
+}
+
+func TestDelayQueueClock(t *testing.T) {
+	clk := newLogClock()
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk.set(start)
+	q := NewQueue[string, any]().(*queue[string, any])
+	q.clock = clk
+
+	// relative times are taken from the clock of the queue when the item is added
+	q.Add("foo", "bar", WithDelay(time.Minute), WithExpiresIn(time.Hour))
+	foo := q.Get("foo")
+	assert.Equal(t, foo.NotBefore(), start.Add(time.Minute))
+	assert.Equal(t, foo.ExpiresAt(), start.Add(time.Hour))
+	assert.Nil(t, q.Peep())
+	clk.advance(start.Add(time.Minute))
+	assert.Equal(t, q.Peep().key, "foo")
+
+	// the option given last wins
+	q.Add("john", "doe", WithNotBefore(start.Add(time.Hour)), WithDelay(time.Second), WithExpiresIn(time.Hour), WithExpiresAt(start.Add(time.Minute)))
+	john := q.Get("john")
+	assert.Equal(t, john.NotBefore(), start.Add(time.Minute+time.Second))
+	assert.Equal(t, john.ExpiresAt(), start.Add(time.Minute))
+}
@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"sort"
+	"time"
+)
+
+// sweepInterval keeps sweeps apart as each one goes through the whole queue, they run on its
+// multiples so replicas sweep alike. Expired items are evicted from the head in between so they
+// are never handed out.
+const sweepInterval = 100 * time.Millisecond
+
+// WithExpiresAt discards an item that was not handed out by t, on top of the ttl of the queue
+func WithExpiresAt(t time.Time) AddOption {
+	return func(c *addConfig) {
+		c.expiresAt, c.expiresIn = t, 0
+	}
+}
+
+// WithExpiresIn discards an item that was not handed out within d from when it is added, by the
+// clock of the queue
+func WithExpiresIn(d time.Duration) AddOption {
+	return func(c *addConfig) {
+		c.expiresAt, c.expiresIn = time.Time{}, d
+	}
+}
+
+// ExpiresAt is when the item expires as set by WithExpiresAt, zero for items that only expire with the queue ttl
+func (i *Item[K, V]) ExpiresAt() time.Time {
+	return i.expiresAt
+}
+
+// deadline is when the item expires in a queue with the given ttl, zero if it never does.
+// The queue ttl counts from when the item became available.
+func (i *Item[K, V]) deadline(ttl time.Duration) time.Time {
+	d := i.expiresAt
+	if ttl > 0 {
+		if t := i.readyAt().Add(ttl); d.IsZero() || t.Before(d) {
+			d = t
+		}
+	}
+	return d
+}
+
+// expired reports whether the item outlived its deadline at now
+func (i *Item[K, V]) expired(ttl time.Duration, now time.Time) bool {
+	d := i.deadline(ttl)
+	return !d.IsZero() && !now.Before(d)
+}
+
+// evictExpired drops expired items from the head of the queue so the next item handed out is live,
+// the sweeper takes care of those further back
+func (q *queue[K, V]) evictExpired() {
+	now := q.now()
+	for v := q.items.peek(); v != nil && v.expired(q.ttl, now); v = q.items.peek() {
+		q.items.pop()
+		q.discard(v)
+	}
+}
+
+// discard forgets an expired item that already left items or the delayed heap
+func (q *queue[K, V]) discard(v *Item[K, V]) {
+	q.leave(opDel, EventExpired, v)
+	q.expired++
+}
+
+// sweep discards the expired items wherever they are in the queue and arms the sweeper for the next
+// one to expire. Leased items and those on their way to the dead letter queue are left alone, they
+// are evicted once requeued.
+func (q *queue[K, V]) sweep() {
+	if q.sweeper != nil {
+		q.sweeper.Stop()
+		q.sweeper = nil
+	}
+	now := q.now()
+	var next time.Time
+	stale := []*Item[K, V]{}
+	for _, v := range q.dict {
+		d := v.deadline(q.ttl)
+		switch {
+		case v.lease != nil || v.moving || d.IsZero():
+		case !now.Before(d):
+			stale = append(stale, v)
+		case next.IsZero() || d.Before(next):
+			next = d
+		}
+	}
+	// in the order they were added so replicas discard them alike
+	sort.Slice(stale, func(a, b int) bool { return stale[a].seq < stale[b].seq })
+	for _, v := range stale {
+		if v.delayed {
+			q.undelay(v)
+		} else {
+			q.items.remove(v)
+		}
+		q.discard(v)
+	}
+	q.armSweep(next)
+}
+
+// armSweep makes sure the sweeper runs once d, when an item expires, is past
+func (q *queue[K, V]) armSweep(d time.Time) {
+	if d.IsZero() {
+		return
+	}
+	if r := d.Truncate(sweepInterval); r.Before(d) {
+		d = r.Add(sweepInterval)
+	}
+	if q.closed || q.sweeper != nil && !q.sweepAt.After(d) {
+		return
+	}
+	if q.sweeper != nil {
+		q.sweeper.Stop()
+	}
+	q.sweepAt = d
+	q.sweeper = q.clock.afterFunc(d.Sub(q.now()), func() {
+		q.mux.Lock()
+		defer q.mux.Unlock()
+		q.sweep()
+	})
+}
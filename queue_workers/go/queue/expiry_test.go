@@ -0,0 +1,70 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiry(t *testing.T) {
+	q := NewQueue[string, any]()
+	s := q.Subscribe()
+	defer s.Close()
+	q.Add("stale", 1, WithExpiresIn(30*time.Millisecond))
+	q.Add("live", 2)
+	q.Add("behind", 3, WithExpiresIn(30*time.Millisecond))
+	q.Add("delayed", 4, WithDelay(time.Hour), WithExpiresIn(30*time.Millisecond))
+	for _, k := range []string{"stale", "live", "behind"} {
+		assert.Equal(t, next(t, s).Item.key, k)
+	}
+	assert.False(t, q.Get("stale").ExpiresAt().IsZero())
+
+	// the sweeper discards expired items wherever they are, delayed ones included
+	for _, k := range []string{"stale", "behind", "delayed"} {
+		e := next(t, s)
+		assert.Equal(t, e.Type, EventExpired)
+		assert.Equal(t, e.Item.key, k)
+	}
+	st := q.Stats()
+	assert.Equal(t, st.Expired, 3)
+	assert.Equal(t, st.Depth, 1)
+	assert.Equal(t, st.Delayed, 0)
+
+	// items past their expiry are never handed out
+	q.Add("gone", 5, WithExpiresAt(time.Now().Add(-time.Second)))
+	assert.Equal(t, q.Pop().key, "live")
+	assert.Nil(t, q.Peep())
+	assert.Equal(t, q.Stats().Expired, 4)
+}
+
+func TestExpiryLeased(t *testing.T) {
+	q := NewQueue[string, any](WithTTL(time.Hour))
+	q.Add("foo", "bar", WithExpiresIn(20*time.Millisecond))
+	assert.Equal(t, q.Get("foo").deadline(time.Hour), q.Get("foo").ExpiresAt())
+	l := mustLease(t, q, time.Minute)
+	require.NotNil(t, l)
+
+	// leased items are left alone until they are requeued
+	time.Sleep(40 * time.Millisecond)
+	assert.NotNil(t, q.Get("foo"))
+	require.NoError(t, q.Nack(l.ID()))
+	assert.Eventually(t, func() bool { return q.Get("foo") == nil }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, q.Stats().Expired, 1)
+}
+
+func TestExpiryDurable(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	expiresAt := time.Now().Add(30 * time.Millisecond)
+	q.Add("foo", "bar", WithExpiresAt(expiresAt))
+	require.NoError(t, q.Close())
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	defer q.Close()
+	assert.True(t, q.Get("foo").ExpiresAt().Equal(expiresAt))
+	assert.Eventually(t, func() bool { return q.Get("foo") == nil }, time.Second, 5*time.Millisecond)
+}
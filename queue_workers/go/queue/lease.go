@@ -0,0 +1,208 @@
+package queue
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrLeaseNotFound is returned when acking or nacking a lease that was already settled or has expired
+var ErrLeaseNotFound = errors.New("lease not found or expired")
+
+// Lease is a claim on an item handed out by Queue.Lease, the item is invisible
+// to other consumers until the lease is acked, nacked or expires
+type Lease[K comparable, V any] struct {
+	id        string
+	item      *Item[K, V]
+	snap      *Item[K, V]
+	expiresAt time.Time
+	timer     stopper
+}
+
+func (l *Lease[K, V]) ID() string {
+	return l.id
+}
+
+// Item is the leased item as it was when the lease was taken
+func (l *Lease[K, V]) Item() *Item[K, V] {
+	return l.snap
+}
+
+func (l *Lease[K, V]) ExpiresAt() time.Time {
+	return l.expiresAt
+}
+
+func newLeaseID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Lease takes the next item out of the queue for at most d, returns nil if the queue is empty.
+// Unless acked in time the item is put back and handed out again.
+func (q *queue[K, V]) Lease(d time.Duration) (*Lease[K, V], error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if err := q.failed(); err != nil {
+		return nil, err
+	}
+	l := q.lease(newLeaseID(), d)
+	if err := q.failed(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// lease hands out the next item under the lease id, it is called with the lock held
+func (q *queue[K, V]) lease(id string, d time.Duration) *Lease[K, V] {
+	q.refresh()
+	v := q.items.pop()
+	if v == nil {
+		return nil
+	}
+	v.attempts++
+	q.delivered++
+	q.log(opAttempt, v)
+	l := &Lease[K, V]{id: id, item: v, snap: v.snapshot(), expiresAt: q.now().Add(d)}
+	q.hold(l)
+	return l
+}
+
+// hold arms the timer of a lease and registers it on its item
+func (q *queue[K, V]) hold(l *Lease[K, V]) {
+	l.timer = q.clock.afterFunc(l.expiresAt.Sub(q.now()), func() { q.expire(l) })
+	l.item.lease = l
+	q.leases[l.id] = l
+}
+
+// Ack settles a lease, the leased item is removed from the queue for good
+func (q *queue[K, V]) Ack(id string, opts ...SettleOption) error {
+	c := settleConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if err := q.failed(); err != nil {
+		return err
+	}
+	l, err := q.settle(id)
+	if err != nil {
+		return err
+	}
+	q.finish(l.item)
+	q.settled(l.item, Succeeded, c.result)
+	return q.failed()
+}
+
+// Nack gives up a lease, the leased item is put back in the queue right away
+// or dead lettered if it ran out of attempts. When the dead letter queue refuses
+// the item it is put back all the same and the error of the dead letter queue returned.
+func (q *queue[K, V]) Nack(id string, opts ...SettleOption) error {
+	c := settleConfig{err: "nacked"}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	q.mux.Lock()
+	if err := q.failed(); err != nil {
+		q.mux.Unlock()
+		return err
+	}
+	l, err := q.settle(id)
+	if err != nil {
+		q.mux.Unlock()
+		return err
+	}
+	q.fail(l.item, c.err)
+	dead := q.retry(l.item)
+	err = q.failed()
+	q.mux.Unlock()
+	if derr := q.deadLetter(dead); err == nil {
+		err = derr
+	}
+	return err
+}
+
+func (q *queue[K, V]) expire(l *Lease[K, V]) {
+	q.mux.Lock()
+	if q.leases[l.id] != l {
+		// settled while the timer was firing
+		q.mux.Unlock()
+		return
+	}
+	q.settle(l.id)
+	q.leasesExpired++
+	q.fail(l.item, errLeaseExpired)
+	dead := q.retry(l.item)
+	q.mux.Unlock()
+	// nobody to report to, items the dead letter queue refuses are redelivered
+	q.deadLetter(dead)
+}
+
+// retry redelivers a failed item unless it used up its attempts, in which case it is returned to be
+// passed to deadLetter once the lock is released. It stays in dict meanwhile, so neither its key nor
+// the room it takes can be claimed by an add before it is known to have reached the dead letter queue.
+func (q *queue[K, V]) retry(v *Item[K, V]) *Item[K, V] {
+	if q.dlq == nil || q.maxAttempts <= 0 || v.attempts < q.maxAttempts {
+		q.requeue(v)
+		return nil
+	}
+	v.moving = true
+	return v
+}
+
+// deadLetter moves an item retry gave up on to the dead letter queue. It is called without the lock
+// held so the lock of dlq is never taken under the one of the queue. The dead letter queue fails the
+// add when it is closed, full or already holds the key, the item is then redelivered rather than lost.
+func (q *queue[K, V]) deadLetter(v *Item[K, V]) error {
+	if v == nil {
+		return nil
+	}
+	err := q.dlq.Add(v.key, v.content, WithPriority(v.priority), WithAttempts(v.attempts))
+	q.mux.Lock()
+	v.moving = false
+	if q.dict[v.key] != v {
+		q.mux.Unlock()
+		// removed on the way, the copy made goes too
+		if err == nil {
+			q.dlq.Remove(v.key)
+		}
+		return nil
+	}
+	defer q.mux.Unlock()
+	if err != nil {
+		q.requeue(v)
+		return fmt.Errorf("failed to dead letter %v, it is redelivered instead: %w", v.key, err)
+	}
+	q.drop(v)
+	q.deadLettered++
+	q.settled(v, Dead, nil)
+	return nil
+}
+
+func (q *queue[K, V]) settle(id string) (*Lease[K, V], error) {
+	l, ok := q.leases[id]
+	if !ok {
+		return nil, ErrLeaseNotFound
+	}
+	l.timer.Stop()
+	l.item.lease = nil
+	delete(q.leases, id)
+	return l, nil
+}
+
+// requeue puts a leased item back as if it was just added, it is logged so the item keeps its new
+// place when a durable queue is reopened
+func (q *queue[K, V]) requeue(v *Item[K, V]) {
+	q.seq++
+	v.seq = q.seq
+	q.log(opRequeue, v)
+	q.items.push(v)
+	q.publish(EventRequeued, v)
+	// the sweeper skipped it while it was leased
+	q.armSweep(v.deadline(q.ttl))
+}
@@ -0,0 +1,56 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLease(t *testing.T) {
+	q := NewQueue[string, any]()
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+
+	l := mustLease(t, q, time.Minute)
+	k, _ := l.Item().KeyValue()
+	assert.Equal(t, k, "foo")
+	// leased items are invisible but still own their key
+	k, _ = q.Peep().KeyValue()
+	assert.Equal(t, k, "john")
+	q.Add("foo", "other")
+	assert.NoError(t, q.Ack(l.ID()))
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+
+	l = mustLease(t, q, time.Minute)
+	assert.NoError(t, q.Nack(l.ID()))
+	assert.ErrorIs(t, q.Nack(l.ID()), ErrLeaseNotFound)
+	k, _ = q.Pop().KeyValue()
+	assert.Equal(t, k, "john")
+	assert.Nil(t, mustLease(t, q, time.Minute))
+}
+
+func TestLeaseExpiry(t *testing.T) {
+	q := NewQueue[string, any]()
+	q.Add("foo", "bar")
+
+	l := mustLease(t, q, 20*time.Millisecond)
+	assert.Nil(t, q.Peep())
+	assert.Eventually(t, func() bool { return q.Peep() != nil }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+
+	redelivered := mustLease(t, q, time.Minute)
+	k, v := redelivered.Item().KeyValue()
+	assert.Equal(t, k, "foo")
+	assert.Equal(t, v, "bar")
+}
+
+func TestLeaseRemove(t *testing.T) {
+	q := NewQueue[string, any]()
+	q.Add("foo", "bar")
+	l := mustLease(t, q, 20*time.Millisecond)
+	q.Remove("foo")
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+	time.Sleep(40 * time.Millisecond)
+	assert.Nil(t, q.Peep())
+}
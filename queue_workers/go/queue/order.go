@@ -0,0 +1,228 @@
+package queue
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Order decides which item Pop and Peep hand out next
+type Order int
+
+const (
+	// FIFO hands out the oldest item first
+	FIFO Order = iota
+	// LIFO hands out the newest item first
+	LIFO
+	// Priority hands out the item with the highest priority first, oldest first on ties
+	Priority
+)
+
+func (o Order) String() string {
+	switch o {
+	case FIFO:
+		return "fifo"
+	case LIFO:
+		return "lifo"
+	case Priority:
+		return "priority"
+	default:
+		return fmt.Sprintf("Order(%d)", int(o))
+	}
+}
+
+// ParseOrder is the inverse of Order.String
+func ParseOrder(s string) (Order, error) {
+	switch strings.ToLower(s) {
+	case "fifo":
+		return FIFO, nil
+	case "lifo":
+		return LIFO, nil
+	case "priority":
+		return Priority, nil
+	default:
+		return 0, fmt.Errorf("unknown queue order %q", s)
+	}
+}
+
+// store holds the items of a queue in the order they are handed out,
+// the owning queue takes care of locking and key uniqueness
+type store[K comparable, V any] interface {
+	push(v *Item[K, V])
+	pop() *Item[K, V]
+	peek() *Item[K, V]
+	remove(v *Item[K, V])
+	len() int
+	// list returns the items in the order they would be popped
+	list() []*Item[K, V]
+}
+
+func newStore[K comparable, V any](o Order) store[K, V] {
+	switch o {
+	case LIFO:
+		return &sliceStore[K, V]{lifo: true}
+	case Priority:
+		return &heapStore[K, V]{}
+	default:
+		return &sliceStore[K, V]{}
+	}
+}
+
+// sliceStore keeps items in insertion order and hands out the head (FIFO) or the tail (LIFO).
+// The FIFO head is popped by moving head past it, the slots before head are only reclaimed once
+// they make up half of the slice so popping stays O(1) amortized either way.
+type sliceStore[K comparable, V any] struct {
+	items []*Item[K, V]
+	head  int
+	lifo  bool
+}
+
+func (s *sliceStore[K, V]) push(v *Item[K, V]) {
+	s.items = append(s.items, v)
+	v.index = len(s.items) - 1
+}
+
+func (s *sliceStore[K, V]) pop() *Item[K, V] {
+	v := s.peek()
+	if v == nil {
+		return nil
+	}
+	if s.lifo {
+		s.items[v.index] = nil
+		s.items = s.items[:v.index]
+	} else {
+		s.items[s.head] = nil
+		s.head++
+		s.compact()
+	}
+	v.index = -1
+	return v
+}
+
+func (s *sliceStore[K, V]) peek() *Item[K, V] {
+	if s.len() == 0 {
+		return nil
+	}
+	if s.lifo {
+		return s.items[len(s.items)-1]
+	}
+	return s.items[s.head]
+}
+
+func (s *sliceStore[K, V]) remove(v *Item[K, V]) {
+	if v == s.peek() {
+		s.pop()
+		return
+	}
+	copy(s.items[v.index:], s.items[v.index+1:])
+	s.items[len(s.items)-1] = nil
+	s.items = s.items[:len(s.items)-1]
+	for i := v.index; i < len(s.items); i++ {
+		s.items[i].index = i
+	}
+	v.index = -1
+}
+
+// compact moves the items back to the start of the slice once half of it is popped heads
+func (s *sliceStore[K, V]) compact() {
+	if s.head*2 < len(s.items) {
+		return
+	}
+	n := copy(s.items, s.items[s.head:])
+	clear(s.items[n:])
+	s.items = s.items[:n]
+	s.head = 0
+	for i, v := range s.items {
+		v.index = i
+	}
+}
+
+func (s *sliceStore[K, V]) len() int {
+	return len(s.items) - s.head
+}
+
+func (s *sliceStore[K, V]) list() []*Item[K, V] {
+	items := make([]*Item[K, V], s.len())
+	for i, v := range s.items[s.head:] {
+		if s.lifo {
+			items[len(items)-1-i] = v
+		} else {
+			items[i] = v
+		}
+	}
+	return items
+}
+
+// heapStore is a binary heap on descending priority then enqueue order,
+// items track their heap index so removing by key is O(log n)
+type heapStore[K comparable, V any] struct {
+	h itemHeap[K, V]
+}
+
+func (s *heapStore[K, V]) push(v *Item[K, V]) {
+	heap.Push(&s.h, v)
+}
+
+func (s *heapStore[K, V]) pop() *Item[K, V] {
+	if len(s.h) == 0 {
+		return nil
+	}
+	return heap.Pop(&s.h).(*Item[K, V])
+}
+
+func (s *heapStore[K, V]) peek() *Item[K, V] {
+	if len(s.h) == 0 {
+		return nil
+	}
+	return s.h[0]
+}
+
+func (s *heapStore[K, V]) remove(v *Item[K, V]) {
+	heap.Remove(&s.h, v.index)
+}
+
+func (s *heapStore[K, V]) len() int {
+	return len(s.h)
+}
+
+func (s *heapStore[K, V]) list() []*Item[K, V] {
+	items := make([]*Item[K, V], len(s.h))
+	copy(items, s.h)
+	sort.Slice(items, func(i, j int) bool { return itemHeap[K, V](items).Less(i, j) })
+	return items
+}
+
+// itemHeap implements heap.Interface
+type itemHeap[K comparable, V any] []*Item[K, V]
+
+func (h itemHeap[K, V]) Len() int { return len(h) }
+
+func (h itemHeap[K, V]) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h itemHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *itemHeap[K, V]) Push(x any) {
+	v := x.(*Item[K, V])
+	v.index = len(*h)
+	*h = append(*h, v)
+}
+
+func (h *itemHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	v := old[n-1]
+	old[n-1] = nil
+	v.index = -1
+	*h = old[:n-1]
+	return v
+}
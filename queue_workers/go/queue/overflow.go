@@ -0,0 +1,139 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Overflow decides what Add does when the queue holds its max size of items
+type Overflow int
+
+const (
+	// Reject fails the add with ErrQueueFull
+	Reject Overflow = iota
+	// DropOldest makes room by discarding the queued item that was added first,
+	// leased and delayed items are never dropped so the add is rejected when only those are left
+	DropOldest
+	// Block waits for an item to leave the queue, for as long as the context given WithContext allows
+	Block
+)
+
+func (o Overflow) String() string {
+	switch o {
+	case Reject:
+		return "reject"
+	case DropOldest:
+		return "drop_oldest"
+	case Block:
+		return "block"
+	default:
+		return fmt.Sprintf("Overflow(%d)", int(o))
+	}
+}
+
+// ParseOverflow is the inverse of Overflow.String
+func ParseOverflow(s string) (Overflow, error) {
+	for _, o := range []Overflow{Reject, DropOldest, Block} {
+		if o.String() == strings.ToLower(s) {
+			return o, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown overflow policy %q", s)
+}
+
+// WithOverflow sets what happens to adds past the max size of the queue, defaults to Reject
+func WithOverflow(o Overflow) Option {
+	return func(c *config) {
+		c.overflow = o
+	}
+}
+
+// WithContext bounds how long Add blocks on a full queue with the Block overflow policy,
+// the add fails with ErrQueueFull once ctx is done
+func WithContext(ctx context.Context) AddOption {
+	return func(c *addConfig) {
+		c.ctx = ctx
+	}
+}
+
+// errWouldBlock fails adds to a full Block replica, applying a change from the log must not wait
+// so the caller waits for room out of it and tries again
+var errWouldBlock = fmt.Errorf("%w, waiting for room", ErrQueueFull)
+
+// reserve makes room for one more item as the overflow policy says, it is called with the lock held
+func (q *queue[K, V]) reserve(ctx context.Context) error {
+	for q.maxSize > 0 && len(q.dict) >= q.maxSize {
+		q.evictExpired()
+		if len(q.dict) < q.maxSize {
+			return nil
+		}
+		switch q.overflow {
+		case DropOldest:
+			if !q.dropOldest() {
+				return ErrQueueFull
+			}
+		case Block:
+			if q.closed {
+				return ErrQueueFull
+			}
+			if q.nowait {
+				return errWouldBlock
+			}
+			if err := q.waitRoom(ctx); err != nil {
+				return err
+			}
+		default:
+			return ErrQueueFull
+		}
+	}
+	return nil
+}
+
+// dropOldest discards the queued item that was added first, returns false if nothing is queued
+func (q *queue[K, V]) dropOldest() bool {
+	var oldest *Item[K, V]
+	for _, v := range q.items.list() {
+		if oldest == nil || v.addedAt.Before(oldest.addedAt) {
+			oldest = v
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+	q.items.remove(oldest)
+	q.drop(oldest)
+	q.dropped++
+	return true
+}
+
+// waitRoom releases the lock until an item leaves the queue or ctx is done
+func (q *queue[K, V]) waitRoom(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if q.room == nil {
+		q.room = make(chan struct{})
+	}
+	room := q.room
+	q.mux.Unlock()
+	var err error
+	select {
+	case <-room:
+	case <-ctx.Done():
+		err = fmt.Errorf("%w: %w", ErrQueueFull, ctx.Err())
+	}
+	q.mux.Lock()
+	if err == nil && q.closed {
+		err = ErrQueueFull
+	}
+	return err
+}
+
+// freeRoom wakes the producers blocked on a full queue, they race for the room made
+func (q *queue[K, V]) freeRoom() {
+	if q.room != nil {
+		close(q.room)
+		q.room = nil
+	}
+}
@@ -0,0 +1,60 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverflowReject(t *testing.T) {
+	q := NewQueue[string, any](WithMaxSize(1))
+	require.NoError(t, q.Add("foo", "bar"))
+	assert.ErrorIs(t, q.Add("john", "doe"), ErrQueueFull)
+	assert.ErrorIs(t, q.Add("foo", "baz"), ErrDuplicateKey)
+	assert.NoError(t, q.Add("foo", "baz", WithUpsert()))
+	assert.Equal(t, q.Stats().Rejected, 1)
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	q := NewQueue[string, any](WithOrder(LIFO), WithMaxSize(2), WithOverflow(DropOldest))
+	require.NoError(t, q.Add("foo", "bar"))
+	require.NoError(t, q.Add("john", "doe"))
+	require.NoError(t, q.Add("fizz", "bazz"))
+	assert.Equal(t, keysOfQueue(q), []string{"fizz", "john"})
+
+	// leased items are not dropped
+	mustLease(t, q, time.Minute)
+	mustLease(t, q, time.Minute)
+	assert.ErrorIs(t, q.Add("biz", "bam"), ErrQueueFull)
+	st := q.Stats()
+	assert.Equal(t, st.Dropped, 1)
+	assert.Equal(t, st.Rejected, 1)
+}
+
+func TestOverflowBlock(t *testing.T) {
+	q := NewQueue[string, any](WithMaxSize(1), WithOverflow(Block))
+	require.NoError(t, q.Add("foo", "bar"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Add("john", "doe", WithContext(ctx)), ErrQueueFull)
+
+	added := make(chan error)
+	go func() { added <- q.Add("john", "doe") }()
+	select {
+	case <-added:
+		t.Fatal("add did not block on a full queue")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, q.Pop().key, "foo")
+	require.NoError(t, <-added)
+	assert.Equal(t, keysOfQueue(q), []string{"john"})
+
+	go func() { added <- q.Add("fizz", "bazz") }()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, q.Close())
+	assert.ErrorIs(t, <-added, ErrQueueFull)
+}
@@ -1,37 +1,278 @@
 package queue
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func keysOf(q *queue[string, any]) []string {
+	keys := []string{}
+	for q.items.len() > 0 {
+		keys = append(keys, q.pop().key)
+	}
+	return keys
+}
+
+// mustLease leases the next item of q, failing the test if leasing fails
+func mustLease(t *testing.T, q Queue[string, any], d time.Duration) *Lease[string, any] {
+	t.Helper()
+	l, err := q.Lease(d)
+	require.NoError(t, err)
+	return l
+}
+
+// mustPopN pops up to n items of q, failing the test if popping fails
+func mustPopN(t *testing.T, q Queue[string, any], n int) []*Item[string, any] {
+	t.Helper()
+	items, err := q.PopN(n)
+	require.NoError(t, err)
+	return items
+}
+
 func TestQueue(t *testing.T) {
-	q := queue{}
+	q := queue[string, any]{config: config{order: LIFO}}
 	q.Init()
-	q.add(&Item{key: "foo", content: "bar"})
-	q.add(&Item{key: "john", content: "doe"})
-	q.add(&Item{key: "fizz", content: "bazz"})
-	assert.Len(t, q.items, 3)
+	q.add(&Item[string, any]{key: "foo", content: "bar"})
+	q.add(&Item[string, any]{key: "john", content: "doe"})
+	q.add(&Item[string, any]{key: "fizz", content: "bazz"})
+	assert.Equal(t, q.items.len(), 3)
 
 	i := q.peep()
 	assert.Equal(t, i.index, 2)
-	assert.Equal(t, i.key, Key("fizz"))
+	assert.Equal(t, i.key, "fizz")
 	assert.Equal(t, i.content, "bazz")
 
 	last := q.pop()
 	assert.Equal(t, last.content, "bazz")
-	assert.Len(t, q.items, 2)
+	assert.Equal(t, q.items.len(), 2)
 
-	q.add(&Item{key: "key", content: "val"})
-	q.add(&Item{key: "will", content: "wang"})
-	q.add(&Item{key: "biz", content: "bam"})
+	q.add(&Item[string, any]{key: "key", content: "val"})
+	q.add(&Item[string, any]{key: "will", content: "wang"})
+	q.add(&Item[string, any]{key: "biz", content: "bam"})
 	removed, err := q.remove("will")
 	assert.NoError(t, err)
 	assert.Equal(t, removed.content, "wang")
-	keys := []Key{}
-	for _, v := range q.items {
+	assert.Equal(t, keysOf(&q), []string{"biz", "key", "john", "foo"})
+}
+
+func TestQueueFIFO(t *testing.T) {
+	q := NewQueue[string, any]().(*queue[string, any])
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Add("fizz", "bazz")
+
+	k, v := q.Peep().KeyValue()
+	assert.Equal(t, k, "foo")
+	assert.Equal(t, v, "bar")
+	assert.Equal(t, q.Pop().key, "foo")
+
+	q.Add("key", "val")
+	q.Remove("fizz")
+	assert.Equal(t, keysOf(q), []string{"john", "key"})
+	assert.Nil(t, q.Pop())
+	assert.Nil(t, q.Peep())
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := NewQueue[string, any](WithOrder(Priority)).(*queue[string, any])
+	q.Add("low", "a", WithPriority(-1))
+	q.Add("first", "b")
+	q.Add("urgent", "c", WithPriority(10))
+	q.Add("second", "d")
+	q.Add("high", "e", WithPriority(5))
+	q.Remove("high")
+
+	assert.Equal(t, q.Peep().key, "urgent")
+	assert.Equal(t, keysOf(q), []string{"urgent", "first", "second", "low"})
+}
+
+func TestQueueDuplicateKey(t *testing.T) {
+	q := NewQueue[string, any]().(*queue[string, any])
+	assert.NoError(t, q.Add("foo", "bar"))
+	assert.ErrorIs(t, q.Add("foo", "baz"), ErrDuplicateKey)
+	_, v := q.Pop().KeyValue()
+	assert.Equal(t, v, "bar")
+	assert.Nil(t, q.Pop())
+
+	_, err := q.Remove("foo")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQueueUpsert(t *testing.T) {
+	q := NewQueue[string, any]()
+	s := q.Subscribe()
+	defer s.Close()
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	peeped := q.Peep()
+	assert.NoError(t, q.Add("foo", "baz", WithUpsert(), WithPriority(5)))
+	// peeped items are copies, later changes do not show through
+	assert.Equal(t, peeped.content, "bar")
+	assert.NoError(t, q.Add("new", "key", WithUpsert()))
+
+	// the upserted item keeps its place and settings
+	v := q.Pop()
+	assert.Equal(t, v.key, "foo")
+	assert.Equal(t, v.content, "baz")
+	assert.Equal(t, v.priority, 0)
+	for _, want := range []EventType{EventAdded, EventAdded, EventUpdated, EventAdded} {
+		assert.Equal(t, next(t, s).Type, want)
+	}
+}
+
+func TestParseOrder(t *testing.T) {
+	for _, o := range []Order{FIFO, LIFO, Priority} {
+		parsed, err := ParseOrder(o.String())
+		assert.NoError(t, err)
+		assert.Equal(t, parsed, o)
+	}
+	_, err := ParseOrder("random")
+	assert.Error(t, err)
+}
+
+func TestQueuePriorityHeap(t *testing.T) {
+	q := NewQueue[string, any](WithOrder(Priority)).(*queue[string, any])
+	for i, p := range []int{3, 1, 4, 1, 5, 9, 2, 6} {
+		q.Add(fmt.Sprintf("k%d", i), i, WithPriority(p))
+	}
+	// remove from the middle of the heap, the rest must still come out in order
+	q.Remove("k2")
+	q.Remove("k5")
+	for _, v := range q.dict {
+		assert.Equal(t, q.items.(*heapStore[string, any]).h[v.index], v)
+	}
+	assert.Equal(t, keysOf(q), []string{"k7", "k4", "k0", "k6", "k1", "k3"})
+}
+
+func TestList(t *testing.T) {
+	for o, want := range map[Order][]string{
+		FIFO:     {"a", "b", "c"},
+		LIFO:     {"c", "b", "a"},
+		Priority: {"b", "a", "c"},
+	} {
+		q := NewQueue[string, any](WithOrder(o))
+		q.Add("a", 1)
+		q.Add("b", 2, WithPriority(1))
+		q.Add("c", 3)
+		keys := []string{}
+		for _, v := range q.List() {
+			keys = append(keys, v.key)
+		}
+		assert.Equal(t, keys, want, o.String())
+	}
+}
+
+func TestQueueMaxSize(t *testing.T) {
+	q := NewQueue[string, any](WithMaxSize(2))
+	assert.NoError(t, q.Add("foo", "bar"))
+	assert.NoError(t, q.Add("john", "doe"))
+	assert.ErrorIs(t, q.Add("fizz", "bazz"), ErrQueueFull)
+	// leased items still take up room
+	l := mustLease(t, q, time.Minute)
+	assert.ErrorIs(t, q.Add("fizz", "bazz"), ErrQueueFull)
+	assert.NoError(t, q.Ack(l.ID()))
+	assert.NoError(t, q.Add("fizz", "bazz"))
+}
+
+func TestQueueTTL(t *testing.T) {
+	q := NewQueue[string, any](WithTTL(30*time.Millisecond), WithMaxSize(2))
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	time.Sleep(40 * time.Millisecond)
+	assert.Empty(t, q.List())
+	// expired items make room for new ones
+	assert.NoError(t, q.Add("fizz", "bazz"))
+	assert.Nil(t, q.Get("foo"))
+	k, _ := q.Pop().KeyValue()
+	assert.Equal(t, k, "fizz")
+	assert.Nil(t, q.Peep())
+}
+
+func TestQueueStats(t *testing.T) {
+	q := NewQueue[string, any]()
+	assert.Equal(t, q.Stats(), Stats{})
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Add("fizz", "bazz")
+	first := q.Get("foo").AddedAt()
+	mustLease(t, q, time.Minute)
+
+	st := q.Stats()
+	assert.Equal(t, st.Depth, 2)
+	assert.Equal(t, st.InFlight, 1)
+	assert.Equal(t, q.InFlight(), 1)
+	assert.Equal(t, st.Oldest, q.Get("john").AddedAt())
+	assert.True(t, st.Oldest.After(first) || st.Oldest.Equal(first))
+	assert.Equal(t, st.Added, 3)
+	assert.Equal(t, st.Delivered, 1)
+}
+
+func TestQueueStatsCounters(t *testing.T) {
+	dlq := NewQueue[string, any]()
+	q := NewQueue[string, any](WithDeadLetter(dlq, 2))
+	s := q.Subscribe()
+	defer s.Close()
+	q.Add("foo", "bar")
+	q.Add("foo", "baz", WithUpsert())
+	q.Add("john", "doe")
+	q.Pop()
+	mustLease(t, q, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return q.Stats().LeasesExpired == 1 }, time.Second, 5*time.Millisecond)
+	q.Nack(mustLease(t, q, time.Minute).ID())
+
+	st := q.Stats()
+	assert.Equal(t, st.Added, 2)
+	assert.Equal(t, st.Delivered, 3)
+	assert.Equal(t, st.LeasesExpired, 1)
+	assert.Equal(t, st.DeadLettered, 1)
+	assert.Equal(t, st.Subscribers, 1)
+	assert.Equal(t, dlq.Stats().Added, 1)
+}
+
+func TestQueueClose(t *testing.T) {
+	q := NewQueue[string, any]()
+	s := q.Subscribe()
+	assert.NoError(t, q.Close())
+	_, ok := <-s.C()
+	assert.False(t, ok)
+	s.Close()
+}
+
+func TestQueuePopN(t *testing.T) {
+	q := NewQueue[string, any](WithOrder(Priority))
+	q.Add("foo", "bar")
+	q.Add("john", "doe", WithPriority(1))
+	q.Add("fizz", "bazz")
+	assert.Empty(t, mustPopN(t, q, 0))
+
+	keys := []string{}
+	for _, v := range mustPopN(t, q, 2) {
 		keys = append(keys, v.key)
 	}
-	assert.Equal(t, keys, []Key{"foo", "john", "key", "biz"})
+	assert.Equal(t, keys, []string{"john", "foo"})
+	assert.Len(t, mustPopN(t, q, 5), 1)
+	assert.Empty(t, mustPopN(t, q, 5))
+}
+
+func TestQueueFIFOPopsInPlace(t *testing.T) {
+	q := NewQueue[string, any]().(*queue[string, any])
+	for i := 0; i < 10; i++ {
+		q.Add(fmt.Sprint(i), i)
+	}
+	for i := 0; i < 4; i++ {
+		q.Pop()
+	}
+	q.Remove("6")
+	q.Add("10", 10)
+	assert.Equal(t, q.Pop().key, "4")
+	q.Remove("9")
+	assert.Equal(t, q.items.len(), 4)
+	for _, v := range q.List() {
+		assert.Equal(t, q.dict[v.key], q.items.(*sliceStore[string, any]).items[q.dict[v.key].index])
+	}
+	assert.Equal(t, keysOf(q), []string{"5", "7", "8", "10"})
 }
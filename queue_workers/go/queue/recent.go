@@ -0,0 +1,68 @@
+package queue
+
+import "time"
+
+// recent remembers values under keys for a window of time after they were put
+type recent[K comparable, T any] struct {
+	window time.Duration
+	m      map[K]recentEntry[T]
+	// keys in the order they were put, a key put again has a later entry covering it
+	order []recentKey[K]
+}
+
+type recentEntry[T any] struct {
+	v  T
+	at time.Time
+}
+
+type recentKey[K comparable] struct {
+	key K
+	at  time.Time
+}
+
+func newRecent[K comparable, T any](window time.Duration) recent[K, T] {
+	return recent[K, T]{window: window, m: map[K]recentEntry[T]{}}
+}
+
+func (r *recent[K, T]) put(k K, v T, at time.Time) {
+	if r.window <= 0 {
+		return
+	}
+	r.m[k] = recentEntry[T]{v: v, at: at}
+	r.order = append(r.order, recentKey[K]{key: k, at: at})
+}
+
+// get returns the value put under k if that was within the window
+func (r *recent[K, T]) get(k K, now time.Time) (T, time.Time, bool) {
+	r.prune(now)
+	e, ok := r.m[k]
+	return e.v, e.at, ok
+}
+
+// prune forgets the keys that fell out of the window
+func (r *recent[K, T]) prune(now time.Time) {
+	n := 0
+	for _, o := range r.order {
+		if now.Sub(o.at) < r.window {
+			break
+		}
+		if e, ok := r.m[o.key]; ok && e.at.Equal(o.at) {
+			delete(r.m, o.key)
+		}
+		n++
+	}
+	r.order = r.order[n:]
+}
+
+// each calls fn on the keys within the window in the order they were put
+func (r *recent[K, T]) each(now time.Time, fn func(k K, v T, at time.Time) error) error {
+	r.prune(now)
+	for _, o := range r.order {
+		if e := r.m[o.key]; e.at.Equal(o.at) {
+			if err := fn(o.key, e.v, e.at); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,666 @@
+package queue
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisKeyPrefix namespaces the keys of every redis queue, see redisPrefix
+	redisKeyPrefix = "queue:"
+	// redisPollInterval is how often delayed items, expired leases and expired items are looked for on top
+	// of the times this process knows of, items and leases may come from other processes
+	redisPollInterval = time.Second
+	// redisBlockPoll is how often an add blocked on a full queue checks for room
+	redisBlockPoll = 50 * time.Millisecond
+)
+
+// redisItem is how an item is kept in redis, its value is kept apart as encoded by the codec.
+// Times are unix milliseconds so scripts can compare them.
+type redisItem struct {
+	Priority  int    `json:"p"`
+	Attempts  int    `json:"a"`
+	AddedAt   int64  `json:"t"`
+	NotBefore int64  `json:"nb"`
+	ExpiresAt int64  `json:"x,omitempty"`
+	Seq       uint64 `json:"s"`
+	Error     string `json:"e"`
+	// Lease is the id of the lease the item is handed out under, empty while it is queued
+	Lease string `json:"l,omitempty"`
+}
+
+// redisStatus is the status of a settled item, kept for the result ttl
+type redisStatus struct {
+	State    string `json:"state"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+	Result   []byte `json:"result,omitempty"`
+	DoneAt   int64  `json:"done_at"`
+}
+
+// redisEvent is published on the events channel of a queue for every change made to it
+type redisEvent struct {
+	Type  string    `json:"t"`
+	Key   string    `json:"k"`
+	Item  redisItem `json:"i"`
+	Value string    `json:"v"`
+}
+
+// redisConfig is the config of a queue as its scripts see it, durations are in milliseconds
+type redisConfig struct {
+	Order     Order  `json:"order"`
+	MaxSize   int    `json:"max_size"`
+	Overflow  int    `json:"overflow"`
+	TTL       int64  `json:"ttl"`
+	Dedup     int64  `json:"dedup"`
+	ResultTTL int64  `json:"result_ttl"`
+	Events    string `json:"events"`
+	Attempts  int    `json:"attempts"`
+	// set when the keys of the dead letter queue follow those of the queue
+	DLQ       bool   `json:"dlq"`
+	DLQOrder  Order  `json:"dlq_order"`
+	DLQEvents string `json:"dlq_events"`
+}
+
+type redisQueue[K comparable, V any] struct {
+	config
+	rdb    redis.UniversalClient
+	prefix string
+	codec  Codec[V]
+	// config and keys passed to every script
+	script string
+	keys   []string
+	ps     *redis.PubSub
+
+	mux    sync.Mutex
+	subs   map[*Subscription[K, V]]struct{}
+	closed bool
+	// first error met by a method with no way to return it, reported by Close
+	err error
+
+	// wakes the scheduler up when a delayed or expiring item is added or a lease taken
+	wake    chan struct{}
+	quit    chan struct{}
+	stopped chan struct{}
+}
+
+// OpenRedis opens a queue kept in redis under name, every process opening the same name on the same
+// server shares its items, leases and settled statuses. Keys are encoded with encoding/json and values with c.
+// The dead letter queue given WithDeadLetter must be a redis queue on the same server named after the queue
+// with DeadLetterName so the two share a hash slot on redis cluster. Subscriptions only see the changes
+// published while the process is connected to the server.
+func OpenRedis[K comparable, V any](rdb redis.UniversalClient, name string, c Codec[V], opts ...Option) (Queue[K, V], error) {
+	q := &redisQueue[K, V]{
+		rdb:     rdb,
+		prefix:  redisPrefix(name),
+		codec:   c,
+		subs:    map[*Subscription[K, V]]struct{}{},
+		wake:    make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(&q.config)
+	}
+	rc := redisConfig{
+		Order:     q.order,
+		MaxSize:   q.maxSize,
+		Overflow:  int(q.overflow),
+		TTL:       millis(q.ttl),
+		Dedup:     millis(q.dedupWindow),
+		ResultTTL: millis(q.resultTTL),
+		Events:    q.prefix + "events",
+	}
+	q.keys = redisKeysOf(q.prefix)
+	if q.config.dlq != nil {
+		dlq, ok := q.config.dlq.(*redisQueue[K, V])
+		if !ok {
+			return nil, errors.New("the dead letter queue of a redis queue must be a redis queue")
+		}
+		if dlq.prefix != redisPrefix(DeadLetterName(name)) {
+			return nil, fmt.Errorf("the dead letter queue of redis queue %s must be named %s", name, DeadLetterName(name))
+		}
+		rc.Attempts, rc.DLQ, rc.DLQOrder, rc.DLQEvents = q.maxAttempts, true, dlq.order, dlq.prefix+"events"
+		q.keys = append(q.keys, redisKeysOf(dlq.prefix)...)
+	}
+	b, err := json.Marshal(rc)
+	if err != nil {
+		return nil, err
+	}
+	q.script = string(b)
+
+	ctx := context.Background()
+	q.ps = rdb.Subscribe(ctx, q.prefix+"events")
+	// wait for the subscription to be in place so no change made after OpenRedis returns is missed
+	if _, err := q.ps.Receive(ctx); err != nil {
+		q.ps.Close()
+		return nil, err
+	}
+	go q.listen()
+	go q.schedule()
+	return q, nil
+}
+
+// DropRedis deletes everything kept in redis for the queue opened under name, it should be closed everywhere first
+func DropRedis(rdb redis.UniversalClient, name string) error {
+	return rdb.Del(context.Background(), redisKeysOf(redisPrefix(name))...).Err()
+}
+
+// redisPrefix is the prefix the keys of the queue named name are kept under. Its hash tag is the name
+// of the queue without its dead letter suffix so a queue and its dead letter queue, which the scripts
+// of the queue change together, hash to the same slot on redis cluster.
+func redisPrefix(name string) string {
+	base := strings.TrimSuffix(name, DeadLetterName(""))
+	return redisKeyPrefix + "{" + base + "}" + name[len(base):] + ":"
+}
+
+// redisKeysOf returns the redisKeys kept under prefix
+func redisKeysOf(prefix string) []string {
+	keys := make([]string, len(redisKeys))
+	for i, k := range redisKeys {
+		keys[i] = prefix + k
+	}
+	return keys
+}
+
+// millis converts a duration to milliseconds, rounding up so short durations are not mistaken for 0
+func millis(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64((d + time.Millisecond - 1) / time.Millisecond)
+}
+
+// unixMillis rounds t up to the millisecond, the zero time is 0
+func unixMillis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return (t.UnixNano() + int64(time.Millisecond) - 1) / int64(time.Millisecond)
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// run runs a script on the keys of the queue with its config as first argument, a nil reply is returned as nil
+func (q *redisQueue[K, V]) run(ctx context.Context, s *redis.Script, args ...any) (any, error) {
+	res, err := s.Run(ctx, q.rdb, q.keys, append([]any{q.script}, args...)...).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return res, err
+}
+
+// fault keeps the first error met by a method that has no way to return it
+func (q *redisQueue[K, V]) fault(err error) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.err == nil {
+		q.err = err
+	}
+}
+
+func (q *redisQueue[K, V]) wakeup() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// decode rebuilds an item from its key, how it is kept and its encoded value
+func (q *redisQueue[K, V]) decode(key string, r redisItem, value string) (*Item[K, V], error) {
+	var k K
+	if err := json.Unmarshal([]byte(key), &k); err != nil {
+		return nil, err
+	}
+	v, err := q.codec.Unmarshal([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	return &Item[K, V]{
+		key:       k,
+		seq:       r.Seq,
+		priority:  r.Priority,
+		attempts:  r.Attempts,
+		addedAt:   fromMillis(r.AddedAt),
+		notBefore: fromMillis(r.NotBefore),
+		expiresAt: fromMillis(r.ExpiresAt),
+		lastErr:   r.Error,
+		content:   v,
+	}, nil
+}
+
+// row decodes a [key, item, value] script reply, ignoring anything after it
+func (q *redisQueue[K, V]) row(reply any) (*Item[K, V], error) {
+	f, ok := reply.([]any)
+	if !ok || len(f) < 3 {
+		return nil, fmt.Errorf("unexpected script reply %v", reply)
+	}
+	key, _ := f[0].(string)
+	raw, _ := f[1].(string)
+	value, _ := f[2].(string)
+	var r redisItem
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		return nil, err
+	}
+	return q.decode(key, r, value)
+}
+
+func (q *redisQueue[K, V]) rows(reply any) ([]*Item[K, V], error) {
+	f, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("unexpected script reply %v", reply)
+	}
+	items := []*Item[K, V]{}
+	for _, r := range f {
+		v, err := q.row(r)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+// Init empties the queue, deleting what it holds in redis
+func (q *redisQueue[K, V]) Init() {
+	if err := q.rdb.Del(context.Background(), redisKeysOf(q.prefix)...).Err(); err != nil {
+		q.fault(err)
+	}
+}
+
+func (q *redisQueue[K, V]) Add(k K, v V, opts ...AddOption) error {
+	c := addConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	key, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	value, err := q.codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+	it, err := json.Marshal(redisItem{Priority: c.priority, Attempts: c.attempts, NotBefore: unixMillis(c.notBefore), ExpiresAt: unixMillis(c.expiresAt)})
+	if err != nil {
+		return err
+	}
+	upsert := "0"
+	if c.upsert {
+		upsert = "1"
+	}
+	for {
+		res, err := q.run(ctx, addScript, key, value, it, upsert, millis(c.delay), millis(c.expiresIn))
+		if err != nil {
+			return err
+		}
+		switch res {
+		case "ok":
+			if !c.notBefore.IsZero() || !c.expiresAt.IsZero() || c.delay > 0 || c.expiresIn > 0 {
+				q.wakeup()
+			}
+			return nil
+		case "duplicate":
+			return fmt.Errorf("%v: %w", k, ErrDuplicateKey)
+		case "done":
+			return fmt.Errorf("%v: %w", k, ErrAlreadyDone)
+		}
+		if q.overflow != Block {
+			return ErrQueueFull
+		}
+		if err := q.waitRoom(ctx); err != nil {
+			q.rdb.HIncrBy(context.Background(), q.prefix+"stats", "rejected", 1)
+			return err
+		}
+	}
+}
+
+// waitRoom waits a while before a blocked add is tried again, room is made by other processes too
+// so there is nothing better than polling to wait on
+func (q *redisQueue[K, V]) waitRoom(ctx context.Context) error {
+	t := time.NewTimer(redisBlockPoll)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-q.quit:
+		return ErrQueueFull
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrQueueFull, ctx.Err())
+	}
+}
+
+func (q *redisQueue[K, V]) Remove(k K) (*Item[K, V], error) {
+	key, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	res, err := q.run(context.Background(), removeScript, key)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, fmt.Errorf("%v: %w", k, ErrNotFound)
+	}
+	return q.row(res)
+}
+
+func (q *redisQueue[K, V]) Pop() *Item[K, V] {
+	items, _ := q.PopN(1)
+	if len(items) == 0 {
+		return nil
+	}
+	return items[0]
+}
+
+func (q *redisQueue[K, V]) PopN(n int) ([]*Item[K, V], error) {
+	if n <= 0 {
+		return []*Item[K, V]{}, nil
+	}
+	res, err := q.run(context.Background(), popScript, n, 0)
+	if err != nil {
+		return nil, err
+	}
+	return q.rows(res)
+}
+
+func (q *redisQueue[K, V]) Peep() *Item[K, V] {
+	return q.lookup(peekScript)
+}
+
+func (q *redisQueue[K, V]) Get(k K) *Item[K, V] {
+	key, err := json.Marshal(k)
+	if err != nil {
+		return nil
+	}
+	return q.lookup(getScript, key)
+}
+
+// lookup runs a script replying with a single item or nil
+func (q *redisQueue[K, V]) lookup(s *redis.Script, args ...any) *Item[K, V] {
+	res, err := q.run(context.Background(), s, args...)
+	if err != nil {
+		q.fault(err)
+		return nil
+	}
+	if res == nil {
+		return nil
+	}
+	v, err := q.row(res)
+	if err != nil {
+		q.fault(err)
+		return nil
+	}
+	return v
+}
+
+func (q *redisQueue[K, V]) List() []*Item[K, V] {
+	res, err := q.run(context.Background(), listScript)
+	if err == nil {
+		var items []*Item[K, V]
+		if items, err = q.rows(res); err == nil {
+			return items
+		}
+	}
+	q.fault(err)
+	return []*Item[K, V]{}
+}
+
+// Lease takes the next item out of the queue for at most d, the lease is reclaimed by whichever
+// process sharing the queue notices it expired first
+func (q *redisQueue[K, V]) Lease(d time.Duration) (*Lease[K, V], error) {
+	id := newLeaseID()
+	res, err := q.run(context.Background(), popScript, 1, millis(d), id)
+	if err != nil {
+		return nil, err
+	}
+	items, err := q.rows(res)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+	q.wakeup()
+	// the lease runs out by the clock of redis, which the script reports rather than ours
+	row := res.([]any)[0].([]any)
+	expiresAt, ok := row[len(row)-1].(int64)
+	if len(row) != 4 || !ok {
+		return nil, fmt.Errorf("unexpected script reply %v", row)
+	}
+	return &Lease[K, V]{id: id, snap: items[0], expiresAt: fromMillis(expiresAt)}, nil
+}
+
+func (q *redisQueue[K, V]) Ack(id string, opts ...SettleOption) error {
+	c := settleConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	// results are kept base64 encoded as the status they belong to is JSON
+	return q.settle(id, "1", base64.StdEncoding.EncodeToString(c.result))
+}
+
+func (q *redisQueue[K, V]) Nack(id string, opts ...SettleOption) error {
+	c := settleConfig{err: "nacked"}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return q.settle(id, "0", c.err)
+}
+
+func (q *redisQueue[K, V]) settle(id, ack, arg string) error {
+	res, err := q.run(context.Background(), settleScript, id, ack, arg)
+	if err != nil {
+		return err
+	}
+	if res != int64(1) {
+		return ErrLeaseNotFound
+	}
+	return nil
+}
+
+func (q *redisQueue[K, V]) Status(k K) (Status, bool) {
+	key, err := json.Marshal(k)
+	if err != nil {
+		return Status{}, false
+	}
+	res, err := q.run(context.Background(), statusScript, key)
+	if err != nil {
+		q.fault(err)
+		return Status{}, false
+	}
+	f, ok := res.([]any)
+	if !ok || len(f) != 2 {
+		return Status{}, false
+	}
+	raw, _ := f[1].(string)
+	if f[0] == "live" {
+		var r redisItem
+		if err := json.Unmarshal([]byte(raw), &r); err != nil {
+			q.fault(err)
+			return Status{}, false
+		}
+		st := Status{State: Pending, Attempts: r.Attempts, LastError: r.Error}
+		switch {
+		case r.Lease != "":
+			st.State = Leased
+		case r.Error != "":
+			st.State = Failed
+		}
+		return st, true
+	}
+	var r redisStatus
+	if err := json.Unmarshal([]byte(raw), &r); err != nil {
+		q.fault(err)
+		return Status{}, false
+	}
+	st := Status{Attempts: r.Attempts, LastError: r.Error, Result: r.Result, DoneAt: fromMillis(r.DoneAt)}
+	for _, s := range []State{Succeeded, Dead} {
+		if s.String() == r.State {
+			st.State = s
+		}
+	}
+	return st, true
+}
+
+// Stats counts what the queue holds across every process sharing it, except for Subscribers
+// which only counts the subscriptions of this process
+func (q *redisQueue[K, V]) Stats() Stats {
+	q.mux.Lock()
+	st := Stats{Subscribers: len(q.subs)}
+	q.mux.Unlock()
+	res, err := q.run(context.Background(), statsScript)
+	if err != nil {
+		q.fault(err)
+		return st
+	}
+	f, ok := res.([]any)
+	if !ok || len(f) != 5 {
+		q.fault(fmt.Errorf("unexpected script reply %v", res))
+		return st
+	}
+	st.Depth, st.InFlight, st.Delayed = toInt(f[0]), toInt(f[1]), toInt(f[2])
+	if oldest, _ := f[3].(string); oldest != "" {
+		var ms int64
+		fmt.Sscan(oldest, &ms)
+		st.Oldest = fromMillis(ms)
+	}
+	counters, _ := f[4].([]any)
+	for i := 0; i+1 < len(counters); i += 2 {
+		var n int
+		fmt.Sscan(fmt.Sprint(counters[i+1]), &n)
+		switch counters[i] {
+		case "added":
+			st.Added = n
+		case "delivered":
+			st.Delivered = n
+		case "leases_expired":
+			st.LeasesExpired = n
+		case "dead_lettered":
+			st.DeadLettered = n
+		case "expired":
+			st.Expired = n
+		case "dropped":
+			st.Dropped = n
+		case "rejected":
+			st.Rejected = n
+		}
+	}
+	return st
+}
+
+func (q *redisQueue[K, V]) InFlight() int {
+	n, err := q.rdb.ZCard(context.Background(), q.prefix+"leases").Result()
+	if err != nil {
+		q.fault(err)
+		return 0
+	}
+	return int(n)
+}
+
+func toInt(v any) int {
+	n, _ := v.(int64)
+	return int(n)
+}
+
+func (q *redisQueue[K, V]) Subscribe() *Subscription[K, V] {
+	s := newSubscription[K, V]()
+	s.detach = func() {
+		q.mux.Lock()
+		delete(q.subs, s)
+		q.mux.Unlock()
+	}
+	q.mux.Lock()
+	if q.closed {
+		s.stop()
+	} else {
+		q.subs[s] = struct{}{}
+	}
+	q.mux.Unlock()
+	go s.pump()
+	return s
+}
+
+// listen fans the events published for the queue out to its subscribers
+func (q *redisQueue[K, V]) listen() {
+	for msg := range q.ps.Channel() {
+		var e redisEvent
+		if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+			q.fault(err)
+			continue
+		}
+		v, err := q.decode(e.Key, e.Item, e.Value)
+		if err != nil {
+			q.fault(err)
+			continue
+		}
+		for _, t := range []EventType{EventAdded, EventRequeued, EventRemoved, EventUpdated, EventExpired} {
+			if t.String() != e.Type {
+				continue
+			}
+			q.mux.Lock()
+			for s := range q.subs {
+				s.push(Event[K, V]{Type: t, Item: v.snapshot()})
+			}
+			q.mux.Unlock()
+		}
+	}
+}
+
+// schedule promotes delayed items, reclaims expired leases and sweeps expired items as they fall due
+func (q *redisQueue[K, V]) schedule() {
+	defer close(q.stopped)
+	for {
+		wait := redisPollInterval
+		res, err := q.run(context.Background(), tickScript)
+		if err != nil {
+			q.fault(err)
+		} else if ms, _ := res.(int64); ms >= 0 && time.Duration(ms)*time.Millisecond < wait {
+			wait = time.Duration(ms) * time.Millisecond
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-q.quit:
+			t.Stop()
+			return
+		case <-t.C:
+		case <-q.wake:
+			t.Stop()
+		}
+	}
+}
+
+// Close ends subscriptions and stops looking for due items, what the queue holds stays in redis
+func (q *redisQueue[K, V]) Close() error {
+	q.mux.Lock()
+	if q.closed {
+		q.mux.Unlock()
+		return nil
+	}
+	q.closed = true
+	for s := range q.subs {
+		s.stop()
+	}
+	q.subs = map[*Subscription[K, V]]struct{}{}
+	q.mux.Unlock()
+	close(q.quit)
+	<-q.stopped
+	err := q.ps.Close()
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	return errors.Join(q.err, err)
+}
@@ -0,0 +1,483 @@
+package queue
+
+import (
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeys are the keys a redis queue keeps under its prefix:
+//
+//	items       hash of key to item, see redisItem
+//	values      hash of key to value as encoded by the codec
+//	ready       sorted set of the items waiting to be handed out, by score then member so the head is
+//	            always the first member, see member
+//	ages        sorted set of the ready items by when they were added
+//	delayed     sorted set of the items held back by their not before time
+//	expiring    sorted set of the items added with an expiry by when they expire
+//	leases      sorted set of lease ids by expiry
+//	leased      hash of lease id to key
+//	seq         counter items are stamped with when they become ready
+//	stats       hash of counters reported by Stats
+//	done        sorted set of the keys done within the dedup window by when it ends
+//	results     hash of key to status of the settled items
+//	results_due sorted set of the keys in results by when their status is forgotten
+//
+// Changes are published on the events channel under the prefix too, see redisEvent.
+var redisKeys = []string{"items", "values", "ready", "ages", "delayed", "expiring", "leases", "leased", "seq", "stats", "done", "results", "results_due"}
+
+// Every change to a redis queue is made by one of these scripts so it is atomic across the processes
+// sharing the queue. KEYS are the redisKeys of the queue followed by those of its dead letter queue
+// when it has one, so every key a script touches is declared and lives in the hash slot of the queue.
+// ARGV[1] is the config of the queue, the arguments of a script follow. Times are taken from the
+// redis server so the processes sharing a queue agree on when leases and items fall due whatever
+// their own clocks say. Orders and overflow policies are passed to the scripts as their numeric values.
+var redisPrelude = `
+-- redis before 5 only lets scripts write after reading the time when replicating their effects
+redis.replicate_commands()
+local cfg = cjson.decode(ARGV[1])
+local clock = redis.call('TIME')
+local now = tonumber(clock[1]) * 1000 + math.floor(tonumber(clock[2]) / 1000)
+
+local names = {'` + strings.Join(redisKeys, "', '") + `'}
+
+-- keyset names the keys of a queue passed in KEYS from offset on, events is its channel
+local function keyset(offset, events)
+	local p = {events = events}
+	for i, name in ipairs(names) do
+		p[name] = KEYS[offset + i]
+	end
+	return p
+end
+
+local q = keyset(0, cfg.events)
+local dlq = nil
+if cfg.dlq then
+	dlq = keyset(#names, cfg.dlq_events)
+end
+
+local function load(p, key)
+	local raw = redis.call('HGET', p.items, key)
+	if not raw then
+		return nil
+	end
+	return cjson.decode(raw)
+end
+
+local function save(p, key, it)
+	redis.call('HSET', p.items, key, cjson.encode(it))
+end
+
+local function count(p, name)
+	redis.call('HINCRBY', p.stats, name, 1)
+end
+
+local function publish(p, t, key, it)
+	local v = redis.call('HGET', p.values, key) or ''
+	redis.call('PUBLISH', p.events, cjson.encode({t = t, k = key, i = it, v = v}))
+end
+
+-- member is the fixed width rank of an item followed by its key, FIFO ranks by seq and LIFO by reverse seq.
+-- Priority queues score items by their negated priority so ties are broken by seq too.
+local function member(order, key, it)
+	local r = it.s
+	if order == 1 then
+		r = 999999999999999 - r
+	end
+	return string.format('%015d', r) .. key
+end
+
+local function keyof(m)
+	return string.sub(m, 16)
+end
+
+local function ready(p, order, key, it)
+	local score = 0
+	if order == 2 then
+		score = -it.p
+	end
+	redis.call('ZADD', p.ready, score, member(order, key, it))
+	redis.call('ZADD', p.ages, it.t, key)
+end
+
+local function unready(p, order, key, it)
+	redis.call('ZREM', p.ready, member(order, key, it))
+	redis.call('ZREM', p.ages, key)
+end
+
+local function head()
+	local m = redis.call('ZRANGE', q.ready, 0, 0)[1]
+	if not m then
+		return nil
+	end
+	return keyof(m)
+end
+
+-- enqueue adds a new item to the queue of keyset p
+local function enqueue(p, order, key, it, value)
+	it.s = redis.call('INCR', p.seq)
+	redis.call('HSET', p.values, key, value)
+	save(p, key, it)
+	count(p, 'added')
+	if it.x then
+		redis.call('ZADD', p.expiring, it.x, key)
+	end
+	if it.nb > now then
+		redis.call('ZADD', p.delayed, it.nb, key)
+		return
+	end
+	ready(p, order, key, it)
+	publish(p, 'added', key, it)
+end
+
+-- forget deletes what is kept of an item
+local function forget(p, key)
+	redis.call('HDEL', p.items, key)
+	redis.call('HDEL', p.values, key)
+	redis.call('ZREM', p.expiring, key)
+end
+
+-- leave forgets an item already taken out of the ready, delayed and leased sets
+local function leave(p, key, it)
+	publish(p, 'removed', key, it)
+	forget(p, key)
+end
+
+-- discard forgets an expired item already taken out of the ready and delayed sets
+local function discard(key, it)
+	publish(q, 'expired', key, it)
+	forget(q, key)
+	count(q, 'expired')
+end
+
+-- expired reports whether an item outlived the queue ttl, counting from when it became available, or its expiry
+local function expired(it)
+	return (cfg.ttl > 0 and now - math.max(it.t, it.nb) >= cfg.ttl) or (it.x ~= nil and it.x <= now)
+end
+
+-- finish drops an item that was handed out for good, remembering its key for the dedup window
+local function finish(key, it)
+	if cfg.dedup > 0 then
+		redis.call('ZADD', q.done, now + cfg.dedup, key)
+	end
+	leave(q, key, it)
+end
+
+-- settled keeps the status of an item that succeeded or died for the result ttl
+local function settled(key, it, state, result)
+	if cfg.result_ttl <= 0 then
+		return
+	end
+	local st = {state = state, attempts = it.a, error = it.e, done_at = now}
+	if result and result ~= '' then
+		st.result = result
+	end
+	redis.call('HSET', q.results, key, cjson.encode(st))
+	redis.call('ZADD', q.results_due, now + cfg.result_ttl, key)
+end
+
+-- forgetDone drops the keys whose dedup window ended and the statuses kept past the result ttl
+local function forgetDone()
+	redis.call('ZREMRANGEBYSCORE', q.done, '-inf', now)
+	for _, key in ipairs(redis.call('ZRANGEBYSCORE', q.results_due, '-inf', now)) do
+		redis.call('HDEL', q.results, key)
+	end
+	redis.call('ZREMRANGEBYSCORE', q.results_due, '-inf', now)
+end
+
+local function unlease(id)
+	redis.call('ZREM', q.leases, id)
+	redis.call('HDEL', q.leased, id)
+end
+
+-- retry redelivers a failed item unless it used up its attempts, in which case it moves to the dead letter queue
+local function retry(key, it)
+	if not dlq or cfg.attempts <= 0 or it.a < cfg.attempts or redis.call('HEXISTS', dlq.items, key) == 1 then
+		it.s = redis.call('INCR', q.seq)
+		save(q, key, it)
+		ready(q, cfg.order, key, it)
+		publish(q, 'requeued', key, it)
+		return
+	end
+	local value = redis.call('HGET', q.values, key)
+	enqueue(dlq, cfg.dlq_order, key, {p = it.p, a = it.a, t = now, nb = 0, e = ''}, value)
+	leave(q, key, it)
+	count(q, 'dead_lettered')
+	settled(key, it, 'dead', nil)
+end
+
+-- reclaim retries the items whose lease ran out
+local function reclaim()
+	for _, id in ipairs(redis.call('ZRANGEBYSCORE', q.leases, '-inf', now)) do
+		local key = redis.call('HGET', q.leased, id)
+		unlease(id)
+		count(q, 'leases_expired')
+		local it = load(q, key)
+		it.l = nil
+		it.e = '` + errLeaseExpired + `'
+		retry(key, it)
+	end
+end
+
+-- promote moves the delayed items that became available into the queue
+local function promote()
+	for _, key in ipairs(redis.call('ZRANGEBYSCORE', q.delayed, '-inf', now)) do
+		redis.call('ZREM', q.delayed, key)
+		local it = load(q, key)
+		ready(q, cfg.order, key, it)
+		publish(q, 'added', key, it)
+	end
+end
+
+-- evict drops expired items from the head of the queue so the next item handed out is live
+local function evict()
+	while true do
+		local key = head()
+		if not key then
+			return
+		end
+		local it = load(q, key)
+		if not expired(it) then
+			return
+		end
+		unready(q, cfg.order, key, it)
+		discard(key, it)
+	end
+end
+
+-- sweep discards the expired items wherever they are in the queue, leased ones are evicted once requeued
+local function sweep()
+	local keys = redis.call('ZRANGEBYSCORE', q.expiring, '-inf', now)
+	if cfg.ttl > 0 then
+		for _, key in ipairs(redis.call('ZRANGEBYSCORE', q.ages, '-inf', now - cfg.ttl)) do
+			table.insert(keys, key)
+		end
+	end
+	for _, key in ipairs(keys) do
+		local it = load(q, key)
+		if it and not it.l and expired(it) then
+			if redis.call('ZREM', q.delayed, key) == 0 then
+				unready(q, cfg.order, key, it)
+			end
+			discard(key, it)
+		end
+	end
+end
+
+local function refresh()
+	reclaim()
+	promote()
+	evict()
+end
+`
+
+func newRedisScript(body string) *redis.Script {
+	return redis.NewScript(redisPrelude + body)
+}
+
+var (
+	// tickScript brings delayed items, leases and expired items up to date, replies with how many
+	// milliseconds until the next one falls due or -1 if none will. Items expiring with the queue
+	// ttl are swept on the poll interval.
+	tickScript = newRedisScript(`
+reclaim()
+promote()
+sweep()
+forgetDone()
+local next = -1
+local function due(first)
+	if first[2] then
+		local wait = math.max(0, tonumber(first[2]) - now)
+		if next < 0 or wait < next then
+			next = wait
+		end
+	end
+end
+for _, set in ipairs({q.delayed, q.leases}) do
+	due(redis.call('ZRANGE', set, 0, 0, 'WITHSCORES'))
+end
+-- leased items stay in expiring past their expiry, they do not fall due until requeued
+due(redis.call('ZRANGEBYSCORE', q.expiring, '(' .. now, '+inf', 'WITHSCORES', 'LIMIT', 0, 1))
+return next
+`)
+
+	// addScript adds ARGV[2] with value ARGV[3] and settings ARGV[4], upserting if ARGV[5] is 1.
+	// Unless 0, ARGV[6] and ARGV[7] delay and expire the item that many milliseconds from now.
+	// Replies with ok, duplicate, done or full.
+	addScript = newRedisScript(`
+local key = ARGV[2]
+local it = load(q, key)
+if it then
+	if ARGV[5] ~= '1' then
+		return 'duplicate'
+	end
+	redis.call('HSET', q.values, key, ARGV[3])
+	publish(q, 'updated', key, it)
+	return 'ok'
+end
+forgetDone()
+if cfg.dedup > 0 and redis.call('ZSCORE', q.done, key) then
+	return 'done'
+end
+if cfg.max_size > 0 and redis.call('HLEN', q.items) >= cfg.max_size then
+	evict()
+	if redis.call('HLEN', q.items) >= cfg.max_size then
+		local oldest = redis.call('ZRANGE', q.ages, 0, 0)[1]
+		if cfg.overflow ~= 1 or not oldest then
+			-- blocked adds are retried, they are counted as rejected once given up on
+			if cfg.overflow ~= 2 then
+				count(q, 'rejected')
+			end
+			return 'full'
+		end
+		local o = load(q, oldest)
+		unready(q, cfg.order, oldest, o)
+		leave(q, oldest, o)
+		count(q, 'dropped')
+	end
+end
+it = cjson.decode(ARGV[4])
+it.t = now
+local delay, expires_in = tonumber(ARGV[6]), tonumber(ARGV[7])
+if delay > 0 then
+	it.nb = now + delay
+end
+if expires_in > 0 then
+	it.x = now + expires_in
+end
+enqueue(q, cfg.order, key, it, ARGV[3])
+return 'ok'
+`)
+
+	// popScript hands out up to ARGV[2] items, leasing them for ARGV[3] milliseconds under
+	// the ids ARGV[4] onwards unless ARGV[3] is 0. Replies with a [key, item, value] row per item,
+	// rows of leased items end with when the lease expires.
+	popScript = newRedisScript(`
+refresh()
+local n = tonumber(ARGV[2])
+local d = tonumber(ARGV[3])
+local out = {}
+for i = 1, n do
+	evict()
+	local key = head()
+	if not key then
+		break
+	end
+	local it = load(q, key)
+	local value = redis.call('HGET', q.values, key)
+	unready(q, cfg.order, key, it)
+	count(q, 'delivered')
+	if d > 0 then
+		local id = ARGV[3 + i]
+		it.a = it.a + 1
+		it.l = id
+		save(q, key, it)
+		redis.call('ZADD', q.leases, now + d, id)
+		redis.call('HSET', q.leased, id, key)
+		table.insert(out, {key, cjson.encode(it), value, now + d})
+	else
+		finish(key, it)
+		table.insert(out, {key, cjson.encode(it), value})
+	end
+end
+return out
+`)
+
+	// peekScript replies with the [key, item, value] row of the next item handed out
+	peekScript = newRedisScript(`
+refresh()
+local key = head()
+if not key then
+	return nil
+end
+return {key, redis.call('HGET', q.items, key), redis.call('HGET', q.values, key)}
+`)
+
+	// settleScript acks the lease ARGV[2] with the base64 result ARGV[4] if ARGV[3] is 1,
+	// otherwise nacks it with the error ARGV[4]. Replies with 0 if the lease is unknown.
+	settleScript = newRedisScript(`
+reclaim()
+local id = ARGV[2]
+local key = redis.call('HGET', q.leased, id)
+if not key then
+	return 0
+end
+unlease(id)
+local it = load(q, key)
+it.l = nil
+if ARGV[3] == '1' then
+	finish(key, it)
+	settled(key, it, 'succeeded', ARGV[4])
+else
+	it.e = ARGV[4]
+	retry(key, it)
+end
+return 1
+`)
+
+	// removeScript drops ARGV[2] wherever it is, replies with its [key, item, value] row
+	removeScript = newRedisScript(`
+local key = ARGV[2]
+local it = load(q, key)
+if not it then
+	return nil
+end
+if it.l then
+	unlease(it.l)
+elseif redis.call('ZREM', q.delayed, key) == 0 then
+	unready(q, cfg.order, key, it)
+end
+local row = {key, cjson.encode(it), redis.call('HGET', q.values, key)}
+leave(q, key, it)
+return row
+`)
+
+	// getScript replies with the [key, item, value] row of ARGV[2]
+	getScript = newRedisScript(`
+local raw = redis.call('HGET', q.items, ARGV[2])
+if not raw then
+	return nil
+end
+return {ARGV[2], raw, redis.call('HGET', q.values, ARGV[2])}
+`)
+
+	// listScript replies with the rows of the ready items in the order they are handed out,
+	// leaving out the expired ones not swept yet
+	listScript = newRedisScript(`
+local out = {}
+for _, m in ipairs(redis.call('ZRANGE', q.ready, 0, -1)) do
+	local key = keyof(m)
+	local raw = redis.call('HGET', q.items, key)
+	if not expired(cjson.decode(raw)) then
+		table.insert(out, {key, raw, redis.call('HGET', q.values, key)})
+	end
+end
+return out
+`)
+
+	// statusScript replies with [live, item] for items in the queue and [done, status] for settled ones
+	statusScript = newRedisScript(`
+local raw = redis.call('HGET', q.items, ARGV[2])
+if raw then
+	return {'live', raw}
+end
+forgetDone()
+raw = redis.call('HGET', q.results, ARGV[2])
+if raw then
+	return {'done', raw}
+end
+return nil
+`)
+
+	// statsScript replies with [depth, in flight, delayed, oldest added at, counters]
+	statsScript = newRedisScript(`
+local oldest = redis.call('ZRANGE', q.ages, 0, 0, 'WITHSCORES')
+return {
+	redis.call('ZCARD', q.ready),
+	redis.call('ZCARD', q.leases),
+	redis.call('ZCARD', q.delayed),
+	oldest[2] or '',
+	redis.call('HGETALL', q.stats),
+}
+`)
+)
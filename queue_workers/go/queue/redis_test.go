@@ -0,0 +1,288 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func startRedis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, rdb
+}
+
+func openRedis(t *testing.T, rdb redis.UniversalClient, name string, opts ...Option) Queue[string, any] {
+	t.Helper()
+	q, err := OpenRedis[string, any](rdb, name, JSONCodec[any]{}, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, q.Close()) })
+	return q
+}
+
+func keys(items []*Item[string, any]) []string {
+	ks := []string{}
+	for _, v := range items {
+		k, _ := v.KeyValue()
+		ks = append(ks, k)
+	}
+	return ks
+}
+
+func TestRedisQueueOrders(t *testing.T) {
+	_, rdb := startRedis(t)
+	for _, tc := range []struct {
+		order Order
+		want  []string
+	}{
+		{FIFO, []string{"a", "b", "c", "d"}},
+		{LIFO, []string{"d", "c", "b", "a"}},
+		{Priority, []string{"c", "a", "d", "b"}},
+	} {
+		q := openRedis(t, rdb, tc.order.String(), WithOrder(tc.order))
+		q.Add("a", 1, WithPriority(1))
+		q.Add("b", 2)
+		q.Add("c", 3, WithPriority(5))
+		q.Add("d", 4, WithPriority(1))
+		assert.Equal(t, keys(q.List()), tc.want, tc.order)
+		k, _ := q.Peep().KeyValue()
+		assert.Equal(t, k, tc.want[0], tc.order)
+		assert.Equal(t, keys(mustPopN(t, q, 10)), tc.want, tc.order)
+		assert.Nil(t, q.Pop())
+	}
+}
+
+func TestRedisQueue(t *testing.T) {
+	_, rdb := startRedis(t)
+	q := openRedis(t, rdb, "q")
+	require.NoError(t, q.Add("foo", "bar"))
+	assert.ErrorIs(t, q.Add("foo", "baz"), ErrDuplicateKey)
+	require.NoError(t, q.Add("foo", "baz", WithUpsert()))
+	q.Add("john", "doe")
+
+	k, v := q.Get("foo").KeyValue()
+	assert.Equal(t, k, "foo")
+	assert.Equal(t, v, "baz")
+	assert.Nil(t, q.Get("nope"))
+	removed, err := q.Remove("john")
+	require.NoError(t, err)
+	_, v = removed.KeyValue()
+	assert.Equal(t, v, "doe")
+	_, err = q.Remove("john")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, v = q.Pop().KeyValue()
+	assert.Equal(t, v, "baz")
+	assert.Nil(t, q.Pop())
+	st := q.Stats()
+	assert.Equal(t, st.Added, 2)
+	assert.Equal(t, st.Delivered, 1)
+}
+
+func TestRedisLease(t *testing.T) {
+	_, rdb := startRedis(t)
+	dlq := openRedis(t, rdb, "q.dlq")
+	q := openRedis(t, rdb, "q", WithDeadLetter(dlq, 2), WithResultTTL(time.Minute))
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+
+	l := mustLease(t, q, time.Minute)
+	k, _ := l.Item().KeyValue()
+	assert.Equal(t, k, "foo")
+	assert.Equal(t, l.Item().Attempts(), 1)
+	st, _ := q.Status("foo")
+	assert.Equal(t, st.State, Leased)
+	assert.Equal(t, q.Stats().InFlight, 1)
+	assert.Equal(t, q.InFlight(), 1)
+	require.NoError(t, q.Ack(l.ID(), WithResult([]byte("done"))))
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+	st, _ = q.Status("foo")
+	assert.Equal(t, st.State, Succeeded)
+	assert.Equal(t, st.Result, []byte("done"))
+
+	// john fails both of its attempts, once nacked and once by its lease running out
+	l = mustLease(t, q, time.Minute)
+	require.NoError(t, q.Nack(l.ID(), WithError("boom")))
+	st, _ = q.Status("john")
+	assert.Equal(t, st.State, Failed)
+	assert.Equal(t, st.LastError, "boom")
+	l = mustLease(t, q, 20*time.Millisecond)
+	assert.Equal(t, l.Item().Attempts(), 2)
+	assert.Eventually(t, func() bool { return dlq.Get("john") != nil }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+	st, _ = q.Status("john")
+	assert.Equal(t, st.State, Dead)
+	assert.Equal(t, st.LastError, errLeaseExpired)
+	assert.Equal(t, dlq.Get("john").Attempts(), 2)
+
+	stats := q.Stats()
+	assert.Equal(t, stats.LeasesExpired, 1)
+	assert.Equal(t, stats.DeadLettered, 1)
+	replayed, err := Replay(dlq, q)
+	assert.NoError(t, err)
+	assert.Equal(t, replayed, []string{"john"})
+	assert.NotNil(t, q.Get("john"))
+}
+
+func TestRedisDelayAndTTL(t *testing.T) {
+	_, rdb := startRedis(t)
+	q := openRedis(t, rdb, "q", WithTTL(50*time.Millisecond))
+	s := q.Subscribe()
+	defer s.Close()
+	q.Add("stale", "soon")
+	q.Add("later", "bar", WithDelay(80*time.Millisecond))
+	assert.Equal(t, q.Stats().Delayed, 1)
+	assert.Equal(t, next(t, s).Type, EventAdded)
+
+	// published once due, by when stale outlived the ttl
+	e := next(t, s)
+	k, _ := e.Item.KeyValue()
+	assert.Equal(t, e.Type, EventAdded)
+	assert.Equal(t, k, "later")
+	assert.False(t, e.Item.NotBefore().IsZero())
+	k, _ = q.Peep().KeyValue()
+	assert.Equal(t, k, "later")
+	assert.Nil(t, q.Get("stale"))
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Empty(t, q.List())
+	assert.Nil(t, q.Pop())
+}
+
+func TestRedisOverflow(t *testing.T) {
+	_, rdb := startRedis(t)
+	q := openRedis(t, rdb, "reject", WithMaxSize(1))
+	q.Add("a", 1)
+	assert.ErrorIs(t, q.Add("b", 2), ErrQueueFull)
+	assert.Equal(t, q.Stats().Rejected, 1)
+
+	q = openRedis(t, rdb, "drop", WithMaxSize(1), WithOverflow(DropOldest))
+	q.Add("a", 1)
+	require.NoError(t, q.Add("b", 2))
+	assert.Nil(t, q.Get("a"))
+	assert.Equal(t, q.Stats().Dropped, 1)
+
+	q = openRedis(t, rdb, "block", WithMaxSize(1), WithOverflow(Block))
+	q.Add("a", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, q.Add("b", 2, WithContext(ctx)), context.DeadlineExceeded)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.Pop()
+	}()
+	require.NoError(t, q.Add("b", 2))
+	assert.NotNil(t, q.Get("b"))
+	assert.Equal(t, q.Stats().Rejected, 1)
+}
+
+func TestRedisDedupWindow(t *testing.T) {
+	mr, rdb := startRedis(t)
+	q := openRedis(t, rdb, "q", WithDedupWindow(time.Minute))
+	q.Add("foo", "bar")
+	q.Pop()
+	assert.ErrorIs(t, q.Add("foo", "bar"), ErrAlreadyDone)
+	// the dedup window goes by the clock of the redis server
+	mr.SetTime(time.Now().Add(time.Minute))
+	assert.NoError(t, q.Add("foo", "bar"))
+}
+
+func TestRedisSharedQueue(t *testing.T) {
+	_, rdb := startRedis(t)
+	a := openRedis(t, rdb, "q")
+	b := openRedis(t, rdb, "q")
+	s := b.Subscribe()
+	defer s.Close()
+
+	require.NoError(t, a.Add("foo", map[string]any{"n": 1.0}))
+	assert.ErrorIs(t, b.Add("foo", "bar"), ErrDuplicateKey)
+	e := next(t, s)
+	k, v := e.Item.KeyValue()
+	assert.Equal(t, e.Type, EventAdded)
+	assert.Equal(t, k, "foo")
+	assert.Equal(t, v, map[string]any{"n": 1.0})
+
+	l := mustLease(t, b, time.Minute)
+	assert.Nil(t, mustLease(t, a, time.Minute))
+	require.NoError(t, a.Ack(l.ID()))
+	assert.Equal(t, next(t, s).Type, EventRemoved)
+	assert.Equal(t, a.Stats().Added, 1)
+	assert.Equal(t, b.Stats().Subscribers, 1)
+
+	require.NoError(t, DropRedis(rdb, "q"))
+	assert.Equal(t, a.Stats(), Stats{})
+}
+
+func TestRedisExpiry(t *testing.T) {
+	_, rdb := startRedis(t)
+	q := openRedis(t, rdb, "q")
+	s := q.Subscribe()
+	defer s.Close()
+	q.Add("live", 1)
+	q.Add("stale", 2, WithExpiresIn(50*time.Millisecond))
+	assert.Equal(t, next(t, s).Type, EventAdded)
+	assert.Equal(t, next(t, s).Type, EventAdded)
+	assert.False(t, q.Get("stale").ExpiresAt().IsZero())
+
+	// swept once due although it is not at the head
+	e := next(t, s)
+	k, _ := e.Item.KeyValue()
+	assert.Equal(t, e.Type, EventExpired)
+	assert.Equal(t, k, "stale")
+	assert.Equal(t, q.Stats().Expired, 1)
+
+	q.Add("gone", 3, WithExpiresAt(time.Now().Add(-time.Second)))
+	k, _ = q.Pop().KeyValue()
+	assert.Equal(t, k, "live")
+	assert.Nil(t, q.Pop())
+	assert.Equal(t, q.Stats().Expired, 2)
+}
+
+func TestRedisDeclaredKeys(t *testing.T) {
+	mr, rdb := startRedis(t)
+	dlq := openRedis(t, rdb, "q.dlq")
+	q := openRedis(t, rdb, "q", WithDeadLetter(dlq, 1), WithResultTTL(time.Minute), WithDedupWindow(time.Minute))
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Add("later", "baz", WithDelay(time.Minute))
+	require.NoError(t, q.Ack(mustLease(t, q, time.Minute).ID(), WithResult([]byte("ok"))))
+	require.NoError(t, q.Nack(mustLease(t, q, time.Minute).ID()))
+	require.NotNil(t, dlq.Get("john"))
+
+	// scripts only touch the keys they are passed, which share the hash tag of the queue
+	declared := map[string]bool{}
+	for _, k := range q.(*redisQueue[string, any]).keys {
+		assert.True(t, strings.HasPrefix(k, "queue:{q}"), k)
+		declared[k] = true
+	}
+	for _, k := range mr.Keys() {
+		assert.True(t, declared[k], k)
+	}
+	_, err := OpenRedis[string, any](rdb, "other", JSONCodec[any]{}, WithDeadLetter(dlq, 1))
+	assert.Error(t, err)
+}
+
+func TestRedisServerClock(t *testing.T) {
+	mr, rdb := startRedis(t)
+	q := openRedis(t, rdb, "q")
+	q.Add("foo", "bar")
+	behind := time.Now().Add(-time.Minute).Truncate(time.Millisecond)
+	mr.SetTime(behind)
+	l := mustLease(t, q, time.Hour)
+	assert.Nil(t, q.Peep())
+	assert.True(t, l.ExpiresAt().Equal(behind.Add(time.Hour)), l.ExpiresAt())
+
+	// leases run out by the clock of the redis server, not the one of the process
+	mr.SetTime(time.Now().Add(2 * time.Hour))
+	k, _ := q.Peep().KeyValue()
+	assert.Equal(t, k, "foo")
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+}
@@ -0,0 +1,447 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaTick is how often a replicated queue checks whether one of its timers fell due
+const replicaTick = 10 * time.Millisecond
+
+// Log orders the changes made to replicated queues, typically with a consensus protocol such as raft,
+// so that every replica applies the same changes in the same order
+type Log interface {
+	// Append commits cmd, a change to the replicated queue name, and returns what
+	// the replica of the calling process replied when applying it
+	Append(name string, cmd []byte) (any, error)
+}
+
+// Replica is the state machine side of a replicated queue, the log applies the changes committed to it
+type Replica interface {
+	// Apply makes a change appended by the queue, its reply is handed back by Log.Append
+	Apply(cmd []byte) any
+	// Snapshot writes the whole state of the queue, leases included
+	Snapshot(w io.Writer) error
+	// Restore replaces the state of the queue with one written by Snapshot
+	Restore(r io.Reader) error
+}
+
+// Replicated is a queue kept in step with its replicas in other processes through a Log
+type Replicated[K comparable, V any] interface {
+	Queue[K, V]
+	Replica
+}
+
+// replicated appends every change to its log and only applies it once committed,
+// reads are served from the local replica which may lag behind the log
+type replicated[K comparable, V any] struct {
+	log   Log
+	name  string
+	local *queue[K, V]
+	clock *logClock
+	// adds to a full Block queue given up on by this process, they never reach the log
+	rejected atomic.Int64
+	quit     chan struct{}
+	once     sync.Once
+	wg       sync.WaitGroup
+}
+
+// command is a change appended to the log, At is when it was made and the time replicas apply it at
+type command struct {
+	Op        string          `json:"op"`
+	At        time.Time       `json:"at"`
+	Key       json.RawMessage `json:"key,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Priority  int             `json:"priority,omitempty"`
+	Attempts  int             `json:"attempts,omitempty"`
+	NotBefore time.Time       `json:"not_before,omitempty"`
+	ExpiresAt time.Time       `json:"expires_at,omitempty"`
+	// resolved against At when applied, so every replica gets the same times
+	Delay     time.Duration `json:"delay,omitempty"`
+	ExpiresIn time.Duration `json:"expires_in,omitempty"`
+	Upsert    bool          `json:"upsert,omitempty"`
+	N         int           `json:"n,omitempty"`
+	Lease     string        `json:"lease,omitempty"`
+	Duration  time.Duration `json:"duration,omitempty"`
+	Result    []byte        `json:"result,omitempty"`
+	Error     string        `json:"error,omitempty"`
+}
+
+const (
+	cmdInit   = "init"
+	cmdAdd    = "add"
+	cmdRemove = "remove"
+	cmdPop    = "pop"
+	cmdLease  = "lease"
+	cmdAck    = "ack"
+	cmdNack   = "nack"
+	// cmdTick only moves the clock of the replicas, firing the timers due
+	cmdTick = "tick"
+)
+
+// reply is what applying a command returns to Log.Append
+type reply[K comparable, V any] struct {
+	items []*Item[K, V]
+	lease *Lease[K, V]
+	err   error
+}
+
+// replicaHeader is the first line of a replica snapshot, the records rebuilding the queue follow it
+type replicaHeader struct {
+	Now           time.Time `json:"now"`
+	Dropped       int       `json:"dropped,omitempty"`
+	Rejected      int       `json:"rejected,omitempty"`
+	Added         int       `json:"added,omitempty"`
+	Delivered     int       `json:"delivered,omitempty"`
+	LeasesExpired int       `json:"leases_expired,omitempty"`
+	DeadLettered  int       `json:"dead_lettered,omitempty"`
+	Expired       int       `json:"expired,omitempty"`
+}
+
+// NewReplicated returns the replica of the queue name kept in step through log, the caller routes
+// the changes committed to the log for name to its Apply. Keys are encoded with encoding/json and
+// values with c. The replica only tells the time from the changes applied, so leases expire and
+// delayed items become available once a tick appended by the process allowed to append reaches it.
+// A dead letter queue passed WithDeadLetter must be a replica on the same log.
+func NewReplicated[K comparable, V any](log Log, name string, c Codec[V], opts ...Option) Replicated[K, V] {
+	opts = append(opts, func(c *config) {
+		// the items dead lettered by a change are moved by the replicas applying it
+		if dlq, ok := c.dlq.(*replicated[K, V]); ok {
+			c.dlq = Queue[K, V](dlq.local)
+		}
+	})
+	q := newQueue[K, V](opts...)
+	q.codec = c
+	q.nowait = true
+	clk := newLogClock()
+	q.clock = clk
+	r := &replicated[K, V]{log: log, name: name, local: q, clock: clk, quit: make(chan struct{})}
+	r.wg.Add(1)
+	go r.tick()
+	return r
+}
+
+// tick appends a tick once a timer of the replica falls due. Processes not allowed to append,
+// raft followers for one, fail to and wait for the tick appended by the one that is.
+func (r *replicated[K, V]) tick() {
+	defer r.wg.Done()
+	t := time.NewTicker(replicaTick)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if at, ok := r.clock.next(); ok && !time.Now().Before(at) {
+				r.propose(command{Op: cmdTick})
+			}
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *replicated[K, V]) propose(c command) (reply[K, V], error) {
+	c.At = time.Now()
+	b, err := json.Marshal(c)
+	if err != nil {
+		return reply[K, V]{}, err
+	}
+	res, err := r.log.Append(r.name, b)
+	if err != nil {
+		return reply[K, V]{}, err
+	}
+	rep, ok := res.(reply[K, V])
+	if !ok {
+		return reply[K, V]{}, fmt.Errorf("unexpected reply %T to %s", res, c.Op)
+	}
+	return rep, rep.err
+}
+
+func (r *replicated[K, V]) Apply(cmd []byte) any {
+	var c command
+	if err := json.Unmarshal(cmd, &c); err != nil {
+		return reply[K, V]{err: fmt.Errorf("invalid command: %w", err)}
+	}
+	r.clock.advance(c.At)
+	q := r.local
+	var k K
+	if c.Key != nil {
+		if err := json.Unmarshal(c.Key, &k); err != nil {
+			return reply[K, V]{err: fmt.Errorf("invalid key in %s command: %w", c.Op, err)}
+		}
+	}
+	switch c.Op {
+	case cmdTick:
+		return reply[K, V]{}
+	case cmdInit:
+		q.Init()
+		return reply[K, V]{}
+	case cmdAdd:
+		v, err := q.codec.Unmarshal(c.Value)
+		if err != nil {
+			return reply[K, V]{err: fmt.Errorf("invalid value of %v: %w", k, err)}
+		}
+		return reply[K, V]{err: q.Add(k, v, func(a *addConfig) {
+			*a = addConfig{priority: c.Priority, attempts: c.Attempts, notBefore: c.NotBefore, expiresAt: c.ExpiresAt, delay: c.Delay, expiresIn: c.ExpiresIn, upsert: c.Upsert}
+		})}
+	case cmdRemove:
+		v, err := q.Remove(k)
+		return reply[K, V]{items: []*Item[K, V]{v}, err: err}
+	case cmdPop:
+		items, err := q.PopN(c.N)
+		return reply[K, V]{items: items, err: err}
+	case cmdLease:
+		q.mux.Lock()
+		defer q.mux.Unlock()
+		return reply[K, V]{lease: q.lease(c.Lease, c.Duration)}
+	case cmdAck:
+		return reply[K, V]{err: q.Ack(c.Lease, WithResult(c.Result))}
+	case cmdNack:
+		return reply[K, V]{err: q.Nack(c.Lease, WithError(c.Error))}
+	default:
+		return reply[K, V]{err: fmt.Errorf("unknown command op %q", c.Op)}
+	}
+}
+
+func (r *replicated[K, V]) Init() {
+	r.propose(command{Op: cmdInit})
+}
+
+func (r *replicated[K, V]) Add(k K, v V, opts ...AddOption) error {
+	c := addConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	key, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	value, err := r.local.codec.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode value of %v: %w", k, err)
+	}
+	for {
+		_, err := r.propose(command{Op: cmdAdd, Key: key, Value: value, Priority: c.priority, Attempts: c.attempts, NotBefore: c.notBefore, ExpiresAt: c.expiresAt, Delay: c.delay, ExpiresIn: c.expiresIn, Upsert: c.upsert})
+		if !errors.Is(err, errWouldBlock) {
+			return err
+		}
+		if err := r.waitRoom(c.ctx); err != nil {
+			r.rejected.Add(1)
+			return err
+		}
+	}
+}
+
+// waitRoom waits for an item to leave the local replica of a full Block queue or ctx to be done
+func (r *replicated[K, V]) waitRoom(ctx context.Context) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	q := r.local
+	q.mux.Lock()
+	if len(q.dict) < q.maxSize {
+		q.mux.Unlock()
+		return nil
+	}
+	if q.room == nil {
+		q.room = make(chan struct{})
+	}
+	room := q.room
+	q.mux.Unlock()
+	select {
+	case <-room:
+		return nil
+	case <-r.quit:
+		return ErrQueueFull
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrQueueFull, ctx.Err())
+	}
+}
+
+func (r *replicated[K, V]) Remove(k K) (*Item[K, V], error) {
+	key, err := json.Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := r.propose(command{Op: cmdRemove, Key: key})
+	if err != nil {
+		return nil, err
+	}
+	return rep.items[0], nil
+}
+
+func (r *replicated[K, V]) Pop() *Item[K, V] {
+	items, _ := r.PopN(1)
+	if len(items) == 0 {
+		return nil
+	}
+	return items[0]
+}
+
+// PopN fails when the pop cannot be appended to the log, when it has no leader for one
+func (r *replicated[K, V]) PopN(n int) ([]*Item[K, V], error) {
+	rep, err := r.propose(command{Op: cmdPop, N: n})
+	if err != nil {
+		return nil, err
+	}
+	return rep.items, nil
+}
+
+// Peep looks at the local replica without bringing it up to date, that is left to the log
+func (r *replicated[K, V]) Peep() *Item[K, V] {
+	items := r.local.List()
+	if len(items) == 0 {
+		return nil
+	}
+	return items[0]
+}
+
+func (r *replicated[K, V]) Get(k K) *Item[K, V] {
+	return r.local.Get(k)
+}
+
+func (r *replicated[K, V]) List() []*Item[K, V] {
+	return r.local.List()
+}
+
+func (r *replicated[K, V]) Lease(d time.Duration) (*Lease[K, V], error) {
+	rep, err := r.propose(command{Op: cmdLease, Lease: newLeaseID(), Duration: d})
+	if err != nil {
+		return nil, err
+	}
+	return rep.lease, nil
+}
+
+func (r *replicated[K, V]) Ack(id string, opts ...SettleOption) error {
+	c := settleConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	_, err := r.propose(command{Op: cmdAck, Lease: id, Result: c.result})
+	return err
+}
+
+func (r *replicated[K, V]) Nack(id string, opts ...SettleOption) error {
+	c := settleConfig{err: "nacked"}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	_, err := r.propose(command{Op: cmdNack, Lease: id, Error: c.err})
+	return err
+}
+
+func (r *replicated[K, V]) Status(k K) (Status, bool) {
+	return r.local.Status(k)
+}
+
+// Stats of the local replica, adds given up on while blocked are only counted by the process making them
+func (r *replicated[K, V]) Stats() Stats {
+	st := r.local.Stats()
+	st.Rejected += int(r.rejected.Load())
+	return st
+}
+
+func (r *replicated[K, V]) InFlight() int {
+	return r.local.InFlight()
+}
+
+func (r *replicated[K, V]) Subscribe() *Subscription[K, V] {
+	return r.local.Subscribe()
+}
+
+func (r *replicated[K, V]) Close() error {
+	r.once.Do(func() { close(r.quit) })
+	r.wg.Wait()
+	return r.local.Close()
+}
+
+func (r *replicated[K, V]) Snapshot(w io.Writer) error {
+	q := r.local
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	enc := json.NewEncoder(w)
+	err := enc.Encode(replicaHeader{
+		Now:           q.now(),
+		Dropped:       q.dropped,
+		Rejected:      q.rejected,
+		Added:         q.added,
+		Delivered:     q.delivered,
+		LeasesExpired: q.leasesExpired,
+		DeadLettered:  q.deadLettered,
+		Expired:       q.expired,
+	})
+	if err != nil {
+		return err
+	}
+	items := make([]*Item[K, V], 0, len(q.dict))
+	for _, v := range q.dict {
+		items = append(items, v)
+	}
+	sort.Slice(items, func(a, b int) bool { return items[a].seq < items[b].seq })
+	if err := q.encodeRecords(enc, items); err != nil {
+		return err
+	}
+	for _, v := range items {
+		if v.lease == nil {
+			continue
+		}
+		rec, err := keyRecord(opLease, v.key)
+		if err != nil {
+			return err
+		}
+		rec.Lease, rec.ExpiresAt = v.lease.id, v.lease.expiresAt
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore replaces the items of the local replica, its subscribers are sent the items restored as added
+func (r *replicated[K, V]) Restore(rd io.Reader) error {
+	q := r.local
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	dec := json.NewDecoder(rd)
+	var h replicaHeader
+	if err := dec.Decode(&h); err != nil {
+		return fmt.Errorf("invalid snapshot: %w", err)
+	}
+	for _, l := range q.leases {
+		l.timer.Stop()
+	}
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+	if q.sweeper != nil {
+		q.sweeper.Stop()
+		q.sweeper = nil
+	}
+	r.clock.set(h.Now)
+	q.seq = 0
+	q.dict = map[K]*Item[K, V]{}
+	q.items = newStore[K, V](q.order)
+	q.leases = map[string]*Lease[K, V]{}
+	q.delayed = dueHeap[K, V]{}
+	q.done = newRecent[K, struct{}](q.dedupWindow)
+	q.results = newRecent[K, Status](q.resultTTL)
+	q.dropped, q.rejected, q.added, q.delivered, q.leasesExpired, q.deadLettered, q.expired = h.Dropped, h.Rejected, h.Added, h.Delivered, h.LeasesExpired, h.DeadLettered, h.Expired
+	for dec.More() {
+		var rec record
+		if err := dec.Decode(&rec); err != nil {
+			return fmt.Errorf("invalid snapshot: %w", err)
+		}
+		if err := q.apply(rec); err != nil {
+			return err
+		}
+	}
+	q.freeRoom()
+	return nil
+}
@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memLog applies every change to all the replicas of a queue in turn and replies with what the
+// first one replied, the tests only make changes through the first replica
+type memLog struct {
+	mux      sync.Mutex
+	replicas map[string][]Replica
+}
+
+func (l *memLog) Append(name string, cmd []byte) (any, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	var first any
+	for i, r := range l.replicas[name] {
+		res := r.Apply(cmd)
+		if i == 0 {
+			first = res
+		}
+	}
+	return first, nil
+}
+
+// replicate returns n replicas of the queue name on l
+func replicate(t *testing.T, l *memLog, name string, n int, opts ...Option) []Replicated[string, any] {
+	t.Helper()
+	rs := []Replicated[string, any]{}
+	for i := 0; i < n; i++ {
+		r := NewReplicated[string, any](l, name, JSONCodec[any]{}, opts...)
+		t.Cleanup(func() { assert.NoError(t, r.Close()) })
+		rs = append(rs, r)
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if l.replicas == nil {
+		l.replicas = map[string][]Replica{}
+	}
+	for _, r := range rs {
+		l.replicas[name] = append(l.replicas[name], r)
+	}
+	return rs
+}
+
+func TestReplicated(t *testing.T) {
+	rs := replicate(t, &memLog{}, "q", 3, WithOrder(Priority), WithResultTTL(time.Minute))
+	q := rs[0]
+	require.NoError(t, q.Add("foo", "bar"))
+	require.NoError(t, q.Add("john", "doe", WithPriority(1)))
+	assert.ErrorIs(t, q.Add("foo", "baz"), ErrDuplicateKey)
+	q.Add("later", "on", WithDelay(time.Hour))
+
+	l := mustLease(t, q, time.Minute)
+	k, _ := l.Item().KeyValue()
+	assert.Equal(t, k, "john")
+	for _, r := range rs {
+		st, _ := r.Status("john")
+		assert.Equal(t, st.State, Leased)
+	}
+	require.NoError(t, q.Ack(l.ID(), WithResult([]byte("done"))))
+	assert.ErrorIs(t, q.Ack(l.ID()), ErrLeaseNotFound)
+	_, err := q.Remove("nope")
+	assert.ErrorIs(t, err, ErrNotFound)
+	k, _ = q.Peep().KeyValue()
+	assert.Equal(t, k, "foo")
+	_, v := q.Pop().KeyValue()
+	assert.Equal(t, v, "bar")
+	assert.Nil(t, q.Pop())
+
+	for _, r := range rs[1:] {
+		st, _ := r.Status("john")
+		assert.Equal(t, st.State, Succeeded)
+		assert.Equal(t, st.Result, []byte("done"))
+		assert.NotNil(t, r.Get("later"))
+		assert.Equal(t, r.Stats(), q.Stats())
+	}
+}
+
+// downLog is a log that lost its leader, no change can be appended to it
+type downLog struct{}
+
+var errNoLeader = errors.New("no leader")
+
+func (downLog) Append(name string, cmd []byte) (any, error) {
+	return nil, errNoLeader
+}
+
+func TestReplicatedLogDown(t *testing.T) {
+	q := NewReplicated[string, any](downLog{}, "q", JSONCodec[any]{})
+	t.Cleanup(func() { assert.NoError(t, q.Close()) })
+	_, err := q.PopN(1)
+	assert.ErrorIs(t, err, errNoLeader)
+	_, err = q.Lease(time.Minute)
+	assert.ErrorIs(t, err, errNoLeader)
+	assert.Nil(t, q.Pop())
+}
+
+func TestReplicatedTimers(t *testing.T) {
+	l := &memLog{}
+	dlqs := replicate(t, l, "q.dlq", 3)
+	rs := []Replicated[string, any]{}
+	for _, dlq := range dlqs {
+		rs = append(rs, replicate(t, l, "q", 1, WithDeadLetter[string, any](dlq, 1), WithResultTTL(time.Minute))...)
+	}
+	q := rs[0]
+	q.Add("foo", "bar")
+	q.Add("later", "on", WithDelay(20*time.Millisecond))
+	assert.NotNil(t, mustLease(t, q, 20*time.Millisecond))
+
+	// the replicas only move on with the ticks appended once the timers fall due
+	for i := range rs {
+		assert.Eventually(t, func() bool { return dlqs[i].Get("foo") != nil }, time.Second, 5*time.Millisecond)
+		assert.Eventually(t, func() bool { return rs[i].Peep() != nil }, time.Second, 5*time.Millisecond)
+		st, _ := rs[i].Status("foo")
+		assert.Equal(t, st.State, Dead)
+		assert.Equal(t, st.LastError, errLeaseExpired)
+		assert.Equal(t, rs[i].Stats().LeasesExpired, 1)
+	}
+}
+
+func TestReplicatedExpiry(t *testing.T) {
+	rs := replicate(t, &memLog{}, "q", 3)
+	rs[0].Add("live", 1)
+	rs[0].Add("stale", 2, WithExpiresIn(20*time.Millisecond))
+
+	// every replica sweeps the item once the tick for its expiry is applied
+	for i := range rs {
+		assert.Eventually(t, func() bool { return rs[i].Get("stale") == nil }, time.Second, 5*time.Millisecond)
+		assert.Equal(t, rs[i].Stats().Expired, 1)
+		assert.NotNil(t, rs[i].Get("live"))
+	}
+}
+
+func TestReplicatedBlock(t *testing.T) {
+	rs := replicate(t, &memLog{}, "q", 2, WithMaxSize(1), WithOverflow(Block))
+	q := rs[0]
+	q.Add("a", 1)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		q.Pop()
+	}()
+	require.NoError(t, q.Add("b", 2))
+	assert.NotNil(t, rs[1].Get("b"))
+	assert.Equal(t, q.Stats().Rejected, 0)
+}
+
+func TestReplicatedSnapshot(t *testing.T) {
+	q := replicate(t, &memLog{}, "q", 1, WithDedupWindow(time.Minute))[0]
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Add("later", "on", WithDelay(time.Hour))
+	q.Add("done", "already")
+	q.Remove("done")
+	q.Add("done", "again")
+	l := mustLease(t, q, time.Minute)
+	q.Pop()
+
+	var buf bytes.Buffer
+	require.NoError(t, q.Snapshot(&buf))
+	r := replicate(t, &memLog{}, "q", 1, WithDedupWindow(time.Minute))[0]
+	r.Add("stale", "item")
+	require.NoError(t, r.Restore(&buf))
+
+	assert.Nil(t, r.Get("stale"))
+	assert.Equal(t, keys(r.List()), keys(q.List()))
+	assert.Equal(t, r.Stats(), q.Stats())
+	assert.ErrorIs(t, r.Add("john", "doe"), ErrAlreadyDone)
+	st, _ := r.Status("foo")
+	assert.Equal(t, st.State, Leased)
+	require.NoError(t, r.Ack(l.ID()))
+	assert.Nil(t, r.Get("foo"))
+}
@@ -1,114 +1,540 @@
 package queue
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
-// this is a stack ...
-type Queue interface {
+var (
+	// ErrQueueFull is returned by Add when the queue holds its max size of items
+	ErrQueueFull = errors.New("queue is full")
+	// ErrDuplicateKey is returned by Add when the key is already present, unless upserting
+	ErrDuplicateKey = errors.New("key is already present in queue")
+	// ErrNotFound is returned when looking for a key that is not in the queue
+	ErrNotFound = errors.New("key is not found in queue")
+)
+
+// Queue is a keyed queue of V values, the order items are handed out in is picked at construction time
+type Queue[K comparable, V any] interface {
 	Init()
-	Add(k Key, v any)
-	Remove(k Key)
-	Pop() *Item
-	Peep() *Item
+	// Add fails with ErrDuplicateKey if k is present, unless WithUpsert is given
+	Add(k K, v V, opts ...AddOption) error
+	// Remove drops an item whether it is queued, delayed or leased, fails with ErrNotFound if k is unknown
+	Remove(k K) (*Item[K, V], error)
+	// Pop pops the next item, nil when the queue is empty or popping failed, PopN tells why
+	Pop() *Item[K, V]
+	// PopN pops up to n items at once, fewer when the queue runs out. Queues replicated or kept in
+	// redis fail when the change cannot be made, nothing is popped then.
+	PopN(n int) ([]*Item[K, V], error)
+	Peep() *Item[K, V]
+	// Get looks up an item whether it is queued or leased, returns nil if the key is unknown
+	Get(k K) *Item[K, V]
+	// List returns the queued items in the order they would be handed out
+	List() []*Item[K, V]
+	// Lease returns nil when the queue is empty, it fails as PopN does
+	Lease(d time.Duration) (*Lease[K, V], error)
+	Ack(id string, opts ...SettleOption) error
+	Nack(id string, opts ...SettleOption) error
+	// Status looks up where an item is in its lifecycle, items that left the queue are only known
+	// when they succeeded or died within the result ttl
+	Status(k K) (Status, bool)
+	Stats() Stats
+	// InFlight is the number of leased items, as reported by Stats without gathering the other stats
+	InFlight() int
+	// Subscribe streams every change made to the queue from now on
+	Subscribe() *Subscription[K, V]
+	// Close releases the resources held by the queue, flushing durable queues to disk
+	Close() error
+}
+
+// config holds the settings Option applies, it does not depend on the key and value types
+// so options can be passed around without spelling them out
+type config struct {
+	order       Order
+	maxAttempts int
+	maxSize     int
+	overflow    Overflow
+	ttl         time.Duration
+	dedupWindow time.Duration
+	resultTTL   time.Duration
+	// a Queue[K, V] of the same types as the queue being configured
+	dlq any
+}
+
+// Option configures a queue created by NewQueue
+type Option func(*config)
+
+// WithOrder sets the order items are handed out in, defaults to FIFO
+func WithOrder(o Order) Option {
+	return func(c *config) {
+		c.order = o
+	}
+}
+
+// WithDeadLetter moves items that failed maxAttempts leases into dlq instead of redelivering them,
+// dlq must hold the same key and value types as the queue it is passed to
+func WithDeadLetter[K comparable, V any](dlq Queue[K, V], maxAttempts int) Option {
+	return func(c *config) {
+		c.dlq = dlq
+		c.maxAttempts = maxAttempts
+	}
+}
+
+// WithMaxSize caps the items held by the queue, leased and delayed ones included, 0 means unbounded.
+// What happens to adds past it is up to WithOverflow.
+func WithMaxSize(n int) Option {
+	return func(c *config) {
+		c.maxSize = n
+	}
 }
 
-type queue struct {
-	mux   sync.RWMutex
-	dict  map[Key]*Item
-	items []*Item
+// WithTTL discards items that were not handed out within d of being added, 0 keeps them forever.
+// Items expire sooner when added WithExpiresAt.
+func WithTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.ttl = d
+	}
+}
+
+// addConfig holds the per item settings AddOption applies
+type addConfig struct {
+	priority  int
+	attempts  int
+	notBefore time.Time
+	expiresAt time.Time
+	// relative to when the item is added by the clock of the queue, see resolve
+	delay     time.Duration
+	expiresIn time.Duration
+	upsert    bool
+	ctx       context.Context
+}
+
+// resolve turns the delay and expiry relative to now into the times they end at
+func (c *addConfig) resolve(now time.Time) {
+	if c.delay > 0 {
+		c.notBefore = now.Add(c.delay)
+	}
+	if c.expiresIn > 0 {
+		c.expiresAt = now.Add(c.expiresIn)
+	}
 }
 
-type Key string
+// AddOption configures a single item passed to Add
+type AddOption func(*addConfig)
 
-type Item struct {
-	key     Key
-	index   int
-	content any
+// WithPriority sets the priority of an item, higher goes first in a Priority queue
+func WithPriority(p int) AddOption {
+	return func(c *addConfig) {
+		c.priority = p
+	}
+}
+
+// WithUpsert replaces the content of an item already present under the key instead of failing,
+// the item keeps its place in the queue along with its other settings
+func WithUpsert() AddOption {
+	return func(c *addConfig) {
+		c.upsert = true
+	}
+}
+
+// WithAttempts carries over the delivery attempts of an item moved from another queue
+func WithAttempts(n int) AddOption {
+	return func(c *addConfig) {
+		c.attempts = n
+	}
 }
 
-func (i *Item) KeyValue() (Key, any) {
+type queue[K comparable, V any] struct {
+	mux sync.RWMutex
+	config
+	seq   uint64
+	dict  map[K]*Item[K, V]
+	items store[K, V]
+	// items handed out by Lease, they stay in dict but not in items until requeued
+	leases map[string]*Lease[K, V]
+	dlq    Queue[K, V]
+	// items added with a not before time in the future, they stay in dict but not in items until due
+	delayed dueHeap[K, V]
+	timer   stopper
+	// runs sweep by sweepAt, when the first item expires
+	sweeper stopper
+	sweepAt time.Time
+	// tells the time, the log of replicated queues keeps their own
+	clock clock
+	// set on replicas, adds to a full Block queue fail with errWouldBlock
+	nowait bool
+	// set on durable queues, every change to dict is recorded in it
+	journal *journal
+	codec   Codec[V]
+	subs    map[*Subscription[K, V]]struct{}
+	closed  bool
+	// closed when an item leaves the queue to wake producers blocked on it being full
+	room     chan struct{}
+	dropped  int
+	rejected int
+	// counters reported by Stats, kept since the queue was opened
+	added         int
+	delivered     int
+	leasesExpired int
+	deadLettered  int
+	expired       int
+	// keys done within the dedup window
+	done recent[K, struct{}]
+	// status of the items that succeeded or died within the result ttl
+	results recent[K, Status]
+}
+
+type Item[K comparable, V any] struct {
+	key       K
+	index     int
+	seq       uint64
+	priority  int
+	attempts  int
+	addedAt   time.Time
+	notBefore time.Time
+	expiresAt time.Time
+	delayed   bool
+	// set while the item is moved to the dead letter queue, it is neither queued, delayed nor leased then
+	moving  bool
+	lease   *Lease[K, V]
+	lastErr string
+	content V
+}
+
+func (i *Item[K, V]) KeyValue() (K, V) {
 	return i.key, i.content
 }
 
-func NewQueue() Queue {
-	q := &queue{}
+func (i *Item[K, V]) Priority() int {
+	return i.priority
+}
+
+// Attempts is how many times the item has been leased
+func (i *Item[K, V]) Attempts() int {
+	return i.attempts
+}
+
+// AddedAt is when the item was enqueued
+func (i *Item[K, V]) AddedAt() time.Time {
+	return i.addedAt
+}
+
+func NewQueue[K comparable, V any](opts ...Option) Queue[K, V] {
+	return newQueue[K, V](opts...)
+}
+
+func newQueue[K comparable, V any](opts ...Option) *queue[K, V] {
+	q := &queue[K, V]{}
+	for _, opt := range opts {
+		opt(&q.config)
+	}
+	if q.config.dlq != nil {
+		q.dlq = q.config.dlq.(Queue[K, V])
+	}
 	q.Init()
 	return q
 }
 
-func (q *queue) Init() {
-	q.dict = map[Key]*Item{}
-	q.items = []*Item{}
+func (q *queue[K, V]) Init() {
+	q.dict = map[K]*Item[K, V]{}
+	q.items = newStore[K, V](q.order)
+	q.leases = map[string]*Lease[K, V]{}
+	q.subs = map[*Subscription[K, V]]struct{}{}
+	q.delayed = dueHeap[K, V]{}
+	q.done = newRecent[K, struct{}](q.dedupWindow)
+	q.results = newRecent[K, Status](q.resultTTL)
 	q.mux = sync.RWMutex{}
+	if q.clock == nil {
+		q.clock = wallClock{}
+	}
 }
 
-func (q *queue) Add(k Key, v any) {
+func (q *queue[K, V]) Add(k K, v V, opts ...AddOption) error {
+	c := addConfig{}
+	for _, opt := range opts {
+		opt(&c)
+	}
 	q.mux.Lock()
 	defer q.mux.Unlock()
-	q.add(&Item{key: k, content: v})
+	c.resolve(q.now())
+	i := &Item[K, V]{key: k, content: v, priority: c.priority, attempts: c.attempts, notBefore: c.notBefore, expiresAt: c.expiresAt}
+	if err := q.failed(); err != nil {
+		return err
+	}
+	if old, ok := q.dict[k]; ok {
+		if !c.upsert {
+			return fmt.Errorf("%v: %w", k, ErrDuplicateKey)
+		}
+		q.update(old, v)
+		return q.failed()
+	}
+	if _, ok := q.doneAt(k); ok {
+		return fmt.Errorf("%v: %w", k, ErrAlreadyDone)
+	}
+	if err := q.reserve(c.ctx); err != nil {
+		if err != errWouldBlock {
+			q.rejected++
+		}
+		return err
+	}
+	if err := q.add(i); err != nil {
+		return err
+	}
+	q.added++
+	return q.failed()
 }
 
-func (q *queue) Remove(k Key) {
+func (q *queue[K, V]) Remove(k K) (*Item[K, V], error) {
 	q.mux.Lock()
 	defer q.mux.Unlock()
-	q.remove(k)
+	if err := q.failed(); err != nil {
+		return nil, err
+	}
+	v, err := q.remove(k)
+	if err != nil {
+		return nil, err
+	}
+	return v, q.failed()
+}
+
+func (q *queue[K, V]) Pop() *Item[K, V] {
+	items, _ := q.PopN(1)
+	if len(items) == 0 {
+		return nil
+	}
+	return items[0]
 }
 
-func (q *queue) Pop() *Item {
+// PopN fails with ErrNotDurable when the items popped from a durable queue could not be logged,
+// they are handed out again once the queue is reopened
+func (q *queue[K, V]) PopN(n int) ([]*Item[K, V], error) {
 	q.mux.Lock()
 	defer q.mux.Unlock()
-	return q.pop()
+	if err := q.failed(); err != nil {
+		return nil, err
+	}
+	q.promoteDue()
+	items := []*Item[K, V]{}
+	for len(items) < n {
+		q.evictExpired()
+		v := q.pop()
+		if v == nil {
+			break
+		}
+		items = append(items, v)
+	}
+	if err := q.failed(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func (q *queue[K, V]) Peep() *Item[K, V] {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	q.refresh()
+	v := q.peep()
+	if v == nil {
+		return nil
+	}
+	return v.snapshot()
 }
 
-func (q *queue) Peep() *Item {
+func (q *queue[K, V]) Get(k K) *Item[K, V] {
 	q.mux.RLock()
 	defer q.mux.RUnlock()
-	return q.peep()
+	v, ok := q.dict[k]
+	if !ok {
+		return nil
+	}
+	return v.snapshot()
 }
 
-func (q *queue) pop() *Item {
-	if len(q.items) == 0 {
-		return nil
+func (q *queue[K, V]) List() []*Item[K, V] {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	now := q.now()
+	items := []*Item[K, V]{}
+	for _, v := range q.items.list() {
+		if !v.expired(q.ttl, now) {
+			items = append(items, v.snapshot())
+		}
 	}
-	last := q.items[len(q.items)-1]
-	delete(q.dict, last.key)
-	dpcopy := q.items[:len(q.items)-1]
-	q.items = dpcopy
-	return last
+	return items
+}
+
+// Stats describes the items held by a queue
+type Stats struct {
+	// Depth is the number of items waiting to be handed out
+	Depth int
+	// InFlight is the number of leased items
+	InFlight int
+	// Delayed is the number of items held back until their not before time
+	Delayed int
+	// Oldest is when the longest waiting item was added, zero when the queue is empty
+	Oldest time.Time
+	// Dropped is the number of items discarded to make room with the DropOldest overflow policy
+	Dropped int
+	// Rejected is the number of adds that failed because the queue was full
+	Rejected int
+	// Added is the number of items added, upserts replacing an item not included
+	Added int
+	// Delivered is the number of items handed out by Pop, PopN and Lease
+	Delivered int
+	// LeasesExpired is the number of leases that ran out before being settled
+	LeasesExpired int
+	// DeadLettered is the number of items moved to the dead letter queue
+	DeadLettered int
+	// Expired is the number of items discarded for outliving the queue ttl or their expiry
+	Expired int
+	// Subscribers is the number of open subscriptions
+	Subscribers int
+}
+
+func (q *queue[K, V]) Stats() Stats {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	st := Stats{
+		Depth:         q.items.len(),
+		InFlight:      len(q.leases),
+		Delayed:       q.delayed.Len(),
+		Dropped:       q.dropped,
+		Rejected:      q.rejected,
+		Added:         q.added,
+		Delivered:     q.delivered,
+		LeasesExpired: q.leasesExpired,
+		DeadLettered:  q.deadLettered,
+		Expired:       q.expired,
+		Subscribers:   len(q.subs),
+	}
+	for _, v := range q.items.list() {
+		if st.Oldest.IsZero() || v.addedAt.Before(st.Oldest) {
+			st.Oldest = v.addedAt
+		}
+	}
+	return st
+}
+
+func (q *queue[K, V]) InFlight() int {
+	q.mux.RLock()
+	defer q.mux.RUnlock()
+	return len(q.leases)
+}
+
+func (q *queue[K, V]) now() time.Time {
+	return q.clock.now()
 }
 
-func (q *queue) peep() *Item {
-	if len(q.items) == 0 {
+// refresh brings the head of the queue up to date before handing out an item
+func (q *queue[K, V]) refresh() {
+	q.promoteDue()
+	q.evictExpired()
+}
+
+func (q *queue[K, V]) pop() *Item[K, V] {
+	v := q.items.pop()
+	if v == nil {
 		return nil
 	}
-	return q.items[len(q.items)-1]
+	q.delivered++
+	q.finish(v)
+	return v
+}
+
+func (q *queue[K, V]) peep() *Item[K, V] {
+	return q.items.peek()
 }
 
-func (q *queue) add(v *Item) error {
+func (q *queue[K, V]) add(v *Item[K, V]) error {
 	if _, ok := q.dict[v.key]; ok {
-		return fmt.Errorf("%s is already present in q", v.key)
+		return fmt.Errorf("%v: %w", v.key, ErrDuplicateKey)
+	}
+	q.seq++
+	v.seq = q.seq
+	if v.addedAt.IsZero() {
+		v.addedAt = q.now()
 	}
-	q.items = append(q.items, v)
-	v.index = len(q.items) - 1
 	q.dict[v.key] = v
+	q.log(opAdd, v)
+	q.armSweep(v.deadline(q.ttl))
+	if v.notBefore.After(q.now()) {
+		q.delay(v)
+		return nil
+	}
+	q.items.push(v)
+	q.publish(EventAdded, v)
 	return nil
 }
 
-func (q *queue) remove(k Key) (*Item, error) {
+// update replaces the content of an item in place
+func (q *queue[K, V]) update(v *Item[K, V], content V) {
+	v.content = content
+	q.log(opSet, v)
+	q.publish(EventUpdated, v)
+}
+
+// drop forgets an item that already left items or its lease
+func (q *queue[K, V]) drop(v *Item[K, V]) {
+	q.leave(opDel, EventRemoved, v)
+}
+
+// leave forgets an item, op is the record it is logged as and t the event it is published as
+func (q *queue[K, V]) leave(op string, t EventType, v *Item[K, V]) {
+	delete(q.dict, v.key)
+	q.log(op, v)
+	q.publish(t, v)
+	q.freeRoom()
+}
+
+func (q *queue[K, V]) remove(k K) (*Item[K, V], error) {
 	v, ok := q.dict[k]
 	if !ok {
-		return nil, fmt.Errorf("%s is not found in queue", k)
+		return nil, fmt.Errorf("%v: %w", k, ErrNotFound)
 	}
-	dpcopy := make([]*Item, len(q.items)-1)
-	for i := 0; i < v.index; i++ {
-		dpcopy[i] = q.items[i]
+	switch {
+	case v.lease != nil:
+		q.settle(v.lease.id)
+	case v.delayed:
+		q.undelay(v)
+	case v.moving:
+		// deadLetter finds it gone
+	default:
+		q.items.remove(v)
 	}
-	for i := v.index + 1; i < len(q.items); i++ {
-		dpcopy[i-1] = q.items[i]
-	}
-	delete(q.dict, k)
-	q.items = dpcopy
+	q.drop(v)
 	return v, nil
 }
+
+// snapshot copies an item so it can be handed out without holding the lock
+func (i *Item[K, V]) snapshot() *Item[K, V] {
+	cp := *i
+	return &cp
+}
+
+// Close stops lease timers and ends subscriptions, durable queues are snapshotted and their log closed
+func (q *queue[K, V]) Close() error {
+	q.mux.Lock()
+	q.closed = true
+	q.schedule()
+	if q.sweeper != nil {
+		q.sweeper.Stop()
+		q.sweeper = nil
+	}
+	for _, l := range q.leases {
+		l.timer.Stop()
+	}
+	for s := range q.subs {
+		s.stop()
+	}
+	q.subs = map[*Subscription[K, V]]struct{}{}
+	q.freeRoom()
+	j := q.journal
+	q.mux.Unlock()
+	if j == nil {
+		return nil
+	}
+	return q.closeJournal(j)
+}
@@ -0,0 +1,118 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is where an item is in its lifecycle
+type State int
+
+const (
+	// Pending items wait to be handed out, delayed ones included
+	Pending State = iota
+	// Leased items are being processed by a consumer
+	Leased
+	// Succeeded items were acked
+	Succeeded
+	// Failed items had their last lease nacked or expire and wait to be handed out again
+	Failed
+	// Dead items ran out of attempts and were moved to the dead letter queue
+	Dead
+)
+
+func (s State) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Leased:
+		return "leased"
+	case Succeeded:
+		return "succeeded"
+	case Failed:
+		return "failed"
+	case Dead:
+		return "dead"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// errLeaseExpired is the last error of items whose lease ran out
+const errLeaseExpired = "lease expired"
+
+// Status describes an item that is in the queue or was settled within the result ttl
+type Status struct {
+	State    State
+	Attempts int
+	// LastError is why the last delivery failed, empty if none did
+	LastError string
+	// Result is what the item was acked with
+	Result []byte
+	// DoneAt is when the item succeeded or died, zero while it is in the queue
+	DoneAt time.Time
+}
+
+// WithResultTTL keeps the status of items that succeeded or died for d so it can be looked up
+// with Status, 0 forgets them right away. Items popped, removed or expired are always forgotten.
+func WithResultTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.resultTTL = d
+	}
+}
+
+// settleConfig holds the settings SettleOption applies
+type settleConfig struct {
+	result []byte
+	err    string
+}
+
+// SettleOption reports the outcome of processing a leased item to Ack or Nack
+type SettleOption func(*settleConfig)
+
+// WithResult attaches the result of processing an item to its ack
+func WithResult(b []byte) SettleOption {
+	return func(c *settleConfig) {
+		c.result = b
+	}
+}
+
+// WithError records why processing an item failed on its nack
+func WithError(err string) SettleOption {
+	return func(c *settleConfig) {
+		c.err = err
+	}
+}
+
+func (q *queue[K, V]) Status(k K) (Status, bool) {
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if v, ok := q.dict[k]; ok {
+		st := Status{State: Pending, Attempts: v.attempts, LastError: v.lastErr}
+		switch {
+		case v.lease != nil:
+			st.State = Leased
+		case v.lastErr != "":
+			st.State = Failed
+		}
+		return st, true
+	}
+	st, _, ok := q.results.get(k, q.now())
+	return st, ok
+}
+
+// settled remembers the status of an item that left the queue for good for the result ttl
+func (q *queue[K, V]) settled(v *Item[K, V], s State, result []byte) {
+	if q.resultTTL <= 0 {
+		return
+	}
+	st := Status{State: s, Attempts: v.attempts, LastError: v.lastErr, Result: result, DoneAt: q.now()}
+	q.results.put(v.key, st, st.DoneAt)
+	q.logResult(v.key, st)
+}
+
+// fail records why the last delivery of a leased item failed
+func (q *queue[K, V]) fail(v *Item[K, V], err string) {
+	v.lastErr = err
+	q.log(opFail, v)
+}
@@ -0,0 +1,69 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	dlq := NewQueue[string, any]()
+	q := NewQueue[string, any](WithDeadLetter(dlq, 2), WithResultTTL(50*time.Millisecond))
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	st, ok := q.Status("foo")
+	require.True(t, ok)
+	assert.Equal(t, st.State, Pending)
+
+	l := mustLease(t, q, time.Minute)
+	st, _ = q.Status("foo")
+	assert.Equal(t, st.State, Leased)
+	require.NoError(t, q.Ack(l.ID(), WithResult([]byte("42"))))
+	st, ok = q.Status("foo")
+	require.True(t, ok)
+	assert.Equal(t, st, Status{State: Succeeded, Attempts: 1, Result: []byte("42"), DoneAt: st.DoneAt})
+
+	require.NoError(t, q.Nack(mustLease(t, q, time.Minute).ID(), WithError("boom")))
+	st, _ = q.Status("john")
+	assert.Equal(t, st.State, Failed)
+	assert.Equal(t, st.LastError, "boom")
+	mustLease(t, q, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		st, _ = q.Status("john")
+		return st.State == Dead
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, st.Attempts, 2)
+	assert.Equal(t, st.LastError, errLeaseExpired)
+
+	time.Sleep(50 * time.Millisecond)
+	_, ok = q.Status("foo")
+	assert.False(t, ok)
+	_, ok = q.Status("john")
+	assert.False(t, ok)
+}
+
+func TestDurableStatus(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{}, WithResultTTL(time.Minute))
+	require.NoError(t, err)
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	require.NoError(t, q.Ack(mustLease(t, q, time.Minute).ID(), WithResult([]byte{0, 1})))
+	require.NoError(t, q.Nack(mustLease(t, q, time.Minute).ID(), WithError("boom")))
+	q.(*queue[string, any]).journal.stop()
+
+	for i := 0; i < 2; i++ {
+		q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{}, WithResultTTL(time.Minute))
+		require.NoError(t, err)
+		st, _ := q.Status("foo")
+		assert.Equal(t, st.State, Succeeded)
+		assert.Equal(t, st.Result, []byte{0, 1})
+		st, _ = q.Status("john")
+		assert.Equal(t, st.State, Failed)
+		assert.Equal(t, st.LastError, "boom")
+		// the second round reads them back from the snapshot
+		require.NoError(t, q.Close())
+	}
+}
@@ -0,0 +1,137 @@
+package queue
+
+import "sync"
+
+// EventType is the kind of change an Event reports
+type EventType int
+
+const (
+	// EventAdded is sent when an item is added to the queue
+	EventAdded EventType = iota
+	// EventRequeued is sent when a leased item is nacked or its lease expires and it is handed out again
+	EventRequeued
+	// EventRemoved is sent when an item leaves the queue for good, be it popped, removed, acked or dead lettered
+	EventRemoved
+	// EventUpdated is sent when the content of an item is replaced by an upsert
+	EventUpdated
+	// EventExpired is sent instead of EventRemoved when an item is discarded for outliving the queue ttl or its expiry
+	EventExpired
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventRequeued:
+		return "requeued"
+	case EventRemoved:
+		return "removed"
+	case EventUpdated:
+		return "updated"
+	case EventExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change to a queue, Item is a copy taken when the change happened
+type Event[K comparable, V any] struct {
+	Type EventType
+	Item *Item[K, V]
+}
+
+// Subscription delivers every change made to a queue after Subscribe returned, in order and exactly once.
+// Events are buffered without bound so a slow subscriber never blocks the queue, Close must be
+// called once done to release them.
+type Subscription[K comparable, V any] struct {
+	// detach unregisters the subscription from its queue
+	detach  func()
+	mux     sync.Mutex
+	pending []Event[K, V]
+	notify  chan struct{}
+	c       chan Event[K, V]
+	done    chan struct{}
+	once    sync.Once
+}
+
+// C is closed after Close is called or the queue is closed
+func (s *Subscription[K, V]) C() <-chan Event[K, V] {
+	return s.c
+}
+
+func (s *Subscription[K, V]) Close() {
+	s.detach()
+	s.stop()
+}
+
+func (s *Subscription[K, V]) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func (q *queue[K, V]) Subscribe() *Subscription[K, V] {
+	s := newSubscription[K, V]()
+	s.detach = func() {
+		q.mux.Lock()
+		delete(q.subs, s)
+		q.mux.Unlock()
+	}
+	q.mux.Lock()
+	q.subs[s] = struct{}{}
+	q.mux.Unlock()
+	go s.pump()
+	return s
+}
+
+// newSubscription returns a subscription whose detach is left to the caller to set before it is handed out
+func newSubscription[K comparable, V any]() *Subscription[K, V] {
+	return &Subscription[K, V]{
+		notify: make(chan struct{}, 1),
+		c:      make(chan Event[K, V]),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *Subscription[K, V]) push(e Event[K, V]) {
+	s.mux.Lock()
+	s.pending = append(s.pending, e)
+	s.mux.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pump moves pending events onto C until the subscription is closed
+func (s *Subscription[K, V]) pump() {
+	defer close(s.c)
+	for {
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+		s.mux.Lock()
+		batch := s.pending
+		s.pending = nil
+		s.mux.Unlock()
+		for _, e := range batch {
+			select {
+			case s.c <- e:
+			case <-s.done:
+				return
+			}
+		}
+	}
+}
+
+// publish fans an event out to every subscriber, it is called with the queue lock held
+func (q *queue[K, V]) publish(t EventType, v *Item[K, V]) {
+	if len(q.subs) == 0 {
+		return
+	}
+	e := Event[K, V]{Type: t, Item: v.snapshot()}
+	for s := range q.subs {
+		s.push(e)
+	}
+}
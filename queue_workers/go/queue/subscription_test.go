@@ -0,0 +1,81 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func next(t *testing.T, s *Subscription[string, any]) Event[string, any] {
+	t.Helper()
+	select {
+	case e := <-s.C():
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+		return Event[string, any]{}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	q := NewQueue[string, any]()
+	q.Add("before", "subscribing")
+	s := q.Subscribe()
+	defer s.Close()
+
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	q.Pop()
+	l := mustLease(t, q, time.Minute)
+	q.Nack(l.ID())
+
+	for _, want := range []struct {
+		typ EventType
+		key string
+	}{
+		{EventAdded, "foo"},
+		{EventAdded, "john"},
+		{EventRemoved, "before"},
+		{EventRequeued, "foo"},
+	} {
+		e := next(t, s)
+		assert.Equal(t, e.Type, want.typ)
+		assert.Equal(t, e.Item.key, want.key)
+	}
+}
+
+func TestSubscribeSlowConsumer(t *testing.T) {
+	q := NewQueue[string, any]()
+	s1, s2 := q.Subscribe(), q.Subscribe()
+	defer s2.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 250; j++ {
+				q.Add(fmt.Sprintf("%d-%d", i, j), j)
+			}
+		}(i)
+	}
+	// nobody reads while adding, the queue must not block on its subscribers
+	wg.Wait()
+	for _, s := range []*Subscription[string, any]{s1, s2} {
+		seen := map[string]bool{}
+		for i := 0; i < 1000; i++ {
+			e := next(t, s)
+			assert.False(t, seen[e.Item.key])
+			seen[e.Item.key] = true
+		}
+	}
+
+	s1.Close()
+	_, ok := <-s1.C()
+	assert.False(t, ok)
+	q.Add("after", "close")
+	assert.Equal(t, next(t, s2).Item.key, "after")
+}
@@ -0,0 +1,533 @@
+package queue
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SyncPolicy decides when writes to the write ahead log are fsynced
+type SyncPolicy int
+
+const (
+	// SyncAlways fsyncs every record before the operation returns
+	SyncAlways SyncPolicy = iota
+	// SyncBatch fsyncs on Durability.SyncInterval, a crash loses at most one interval of writes
+	SyncBatch
+	// SyncNone leaves flushing to the OS
+	SyncNone
+)
+
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncAlways:
+		return "always"
+	case SyncBatch:
+		return "batch"
+	case SyncNone:
+		return "none"
+	default:
+		return fmt.Sprintf("SyncPolicy(%d)", int(p))
+	}
+}
+
+// ParseSyncPolicy is the inverse of SyncPolicy.String
+func ParseSyncPolicy(s string) (SyncPolicy, error) {
+	for _, p := range []SyncPolicy{SyncAlways, SyncBatch, SyncNone} {
+		if p.String() == s {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown sync policy %q", s)
+}
+
+// Durability configures how a durable queue persists its state
+type Durability struct {
+	Sync SyncPolicy
+	// SyncInterval is how often a SyncBatch log is fsynced, defaults to 100ms
+	SyncInterval time.Duration
+	// SnapshotInterval is how often the log is compacted into a snapshot, 0 only snapshots on Close
+	SnapshotInterval time.Duration
+}
+
+// ErrNotDurable is returned by the changes to a durable queue that could not be written to its log.
+// The queue is failed from then on, it refuses every later change and Close reports what went wrong.
+var ErrNotDurable = errors.New("change to durable queue was not persisted")
+
+const (
+	snapshotFile        = "snapshot.json"
+	defaultSyncInterval = 100 * time.Millisecond
+)
+
+// Codec encodes the values of a durable queue, the encoding must be valid JSON
+// as it is embedded as is in the lines of the snapshot and write ahead log
+type Codec[V any] interface {
+	Marshal(v V) ([]byte, error)
+	Unmarshal(b []byte) (V, error)
+}
+
+// JSONCodec encodes values with encoding/json
+type JSONCodec[V any] struct{}
+
+func (JSONCodec[V]) Marshal(v V) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec[V]) Unmarshal(b []byte) (V, error) {
+	var v V
+	err := json.Unmarshal(b, &v)
+	return v, err
+}
+
+// OpenDurable opens a queue whose state is kept in dir as a snapshot plus a write ahead log
+// of every change made since, both are replayed before it is returned.
+// Keys are encoded with encoding/json and values with c.
+// Leases are not persisted, items leased when the process stopped are handed out again.
+// Changes fail with ErrNotDurable once a write to the log failed.
+func OpenDurable[K comparable, V any](dir string, d Durability, c Codec[V], opts ...Option) (Queue[K, V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if d.SyncInterval <= 0 {
+		d.SyncInterval = defaultSyncInterval
+	}
+	q := newQueue[K, V](opts...)
+	q.codec = c
+	gen, err := q.restore(dir)
+	if err != nil {
+		return nil, err
+	}
+	j, err := openJournal(dir, gen, d)
+	if err != nil {
+		return nil, err
+	}
+	q.journal = j
+	j.start(&q.mux, q.snapshot)
+	return q, nil
+}
+
+// record is a single line of the snapshot or the write ahead log, ExpiresAt is when the lease
+// of a lease record or the item of an add record expires
+type record struct {
+	Op        string          `json:"op"`
+	Gen       uint64          `json:"gen,omitempty"`
+	Key       json.RawMessage `json:"key,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Priority  int             `json:"priority,omitempty"`
+	Attempts  int             `json:"attempts,omitempty"`
+	AddedAt   time.Time       `json:"added_at,omitempty"`
+	NotBefore time.Time       `json:"not_before,omitempty"`
+	DoneAt    time.Time       `json:"done_at,omitempty"`
+	State     State           `json:"state,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Result    []byte          `json:"result,omitempty"`
+	Lease     string          `json:"lease,omitempty"`
+	ExpiresAt time.Time       `json:"expires_at,omitempty"`
+}
+
+const (
+	opSnapshot = "snapshot"
+	opAdd      = "add"
+	opSet      = "set"
+	opDel      = "del"
+	opAttempt  = "attempt"
+	// opDone is an item handed out for good whose key is remembered for the dedup window
+	opDone = "done"
+	// opFail is a failed delivery of an item
+	opFail = "fail"
+	// opResult is the status of an item that succeeded or died, kept for the result ttl
+	opResult = "result"
+	// opLease is a lease held on an item, only replicas snapshot leases
+	opLease = "lease"
+	// opRequeue is an item put back at the end of the queue after a failed delivery
+	opRequeue = "requeue"
+)
+
+// record describes a change to v, add records carry the whole item so it can be rebuilt from them
+func (q *queue[K, V]) record(op string, v *Item[K, V]) (record, error) {
+	r, err := keyRecord(op, v.key)
+	if err != nil {
+		return record{}, err
+	}
+	if op == opAdd || op == opSet {
+		if r.Value, err = q.codec.Marshal(v.content); err != nil {
+			return record{}, fmt.Errorf("failed to encode value of %v: %w", v.key, err)
+		}
+	}
+	if op == opAdd {
+		r.Priority, r.Attempts, r.AddedAt, r.NotBefore, r.ExpiresAt = v.priority, v.attempts, v.addedAt, v.notBefore, v.expiresAt
+	}
+	if op == opAdd || op == opFail {
+		r.Error = v.lastErr
+	}
+	if op == opDone {
+		r.DoneAt = q.done.m[v.key].at
+	}
+	return r, nil
+}
+
+func keyRecord[K comparable](op string, k K) (record, error) {
+	b, err := json.Marshal(k)
+	if err != nil {
+		return record{}, err
+	}
+	return record{Op: op, Key: b}, nil
+}
+
+func resultRecord[K comparable](k K, st Status) (record, error) {
+	r, err := keyRecord(opResult, k)
+	if err != nil {
+		return record{}, err
+	}
+	r.State, r.Attempts, r.Error, r.Result, r.DoneAt = st.State, st.Attempts, st.LastError, st.Result, st.DoneAt
+	return r, nil
+}
+
+// journal appends records to the write ahead log of generation gen,
+// it is only used with the queue lock held
+type journal struct {
+	dir  string
+	gen  uint64
+	conf Durability
+	f    *os.File
+	// set by the first failed write, the queue refuses changes from then on and Close reports it
+	err   error
+	dirty bool
+	done  chan struct{}
+	once  sync.Once
+	wg    sync.WaitGroup
+}
+
+func walFile(gen uint64) string {
+	return fmt.Sprintf("wal-%d.log", gen)
+}
+
+func openJournal(dir string, gen uint64, d Durability) (*journal, error) {
+	f, err := os.OpenFile(filepath.Join(dir, walFile(gen)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{dir: dir, gen: gen, conf: d, f: f, done: make(chan struct{})}, nil
+}
+
+func (j *journal) append(r record) {
+	if j.err != nil {
+		return
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		j.err = err
+		return
+	}
+	if _, err := j.f.Write(append(b, '\n')); err != nil {
+		j.err = err
+		return
+	}
+	if j.conf.Sync == SyncAlways {
+		j.err = j.f.Sync()
+		return
+	}
+	j.dirty = true
+}
+
+func (j *journal) sync() {
+	if j.err != nil || !j.dirty {
+		return
+	}
+	j.err = j.f.Sync()
+	j.dirty = false
+}
+
+// start runs the batch fsync and periodic snapshot loops until close
+func (j *journal) start(mux sync.Locker, snapshot func() error) {
+	loop := func(interval time.Duration, fn func()) {
+		j.wg.Add(1)
+		go func() {
+			defer j.wg.Done()
+			t := time.NewTicker(interval)
+			defer t.Stop()
+			for {
+				select {
+				case <-t.C:
+					mux.Lock()
+					fn()
+					mux.Unlock()
+				case <-j.done:
+					return
+				}
+			}
+		}()
+	}
+	if j.conf.Sync == SyncBatch {
+		loop(j.conf.SyncInterval, j.sync)
+	}
+	if j.conf.SnapshotInterval > 0 {
+		loop(j.conf.SnapshotInterval, func() {
+			if err := snapshot(); err != nil && j.err == nil {
+				j.err = err
+			}
+		})
+	}
+}
+
+// log records a change to v in the write ahead log of a durable queue
+func (q *queue[K, V]) log(op string, v *Item[K, V]) {
+	if q.journal == nil {
+		return
+	}
+	r, err := q.record(op, v)
+	q.logRecord(r, err)
+}
+
+// logResult records the status of an item that succeeded or died
+func (q *queue[K, V]) logResult(k K, st Status) {
+	if q.journal == nil {
+		return
+	}
+	r, err := resultRecord(k, st)
+	q.logRecord(r, err)
+}
+
+// failed returns the error that failed the log of a durable queue, nil while it is fine.
+// Changes check it before being made and return it when they could not be logged.
+func (q *queue[K, V]) failed() error {
+	if q.journal == nil || q.journal.err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrNotDurable, q.journal.err)
+}
+
+func (q *queue[K, V]) logRecord(r record, err error) {
+	if err != nil {
+		if q.journal.err == nil {
+			q.journal.err = err
+		}
+		return
+	}
+	q.journal.append(r)
+}
+
+// snapshot writes the whole queue to a new snapshot and switches to a fresh log,
+// the snapshot is renamed into place before the old log is dropped so a crash
+// at any point leaves a consistent snapshot and log pair behind
+func (q *queue[K, V]) snapshot() error {
+	j := q.journal
+	if j.err != nil {
+		return j.err
+	}
+	items := make([]*Item[K, V], 0, len(q.dict))
+	for _, v := range q.dict {
+		items = append(items, v)
+	}
+	// replaying in enqueue order rebuilds the same order, leased items included
+	sort.Slice(items, func(a, b int) bool { return items[a].seq < items[b].seq })
+
+	next := j.gen + 1
+	tmp := filepath.Join(j.dir, snapshotFile+".tmp")
+	if err := q.writeRecords(tmp, next, items); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(j.dir, snapshotFile)); err != nil {
+		return err
+	}
+	nj, err := openJournal(j.dir, next, j.conf)
+	if err != nil {
+		return err
+	}
+	j.f.Close()
+	os.Remove(filepath.Join(j.dir, walFile(j.gen)))
+	j.f, j.gen, j.dirty = nj.f, next, false
+	return nil
+}
+
+func (q *queue[K, V]) writeRecords(path string, gen uint64, items []*Item[K, V]) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(record{Op: opSnapshot, Gen: gen}); err != nil {
+		return err
+	}
+	if err := q.encodeRecords(enc, items); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// encodeRecords writes the records rebuilding items along with the keys done and the results kept
+func (q *queue[K, V]) encodeRecords(enc *json.Encoder, items []*Item[K, V]) error {
+	for _, v := range items {
+		r, err := q.record(opAdd, v)
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	now := q.now()
+	err := q.done.each(now, func(k K, _ struct{}, at time.Time) error {
+		r, err := keyRecord(opDone, k)
+		if err != nil {
+			return err
+		}
+		r.DoneAt = at
+		return enc.Encode(r)
+	})
+	if err != nil {
+		return err
+	}
+	return q.results.each(now, func(k K, st Status, _ time.Time) error {
+		r, err := resultRecord(k, st)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(r)
+	})
+}
+
+// restore loads the snapshot and replays the log it points at, returning the log generation
+func (q *queue[K, V]) restore(dir string) (uint64, error) {
+	var gen uint64
+	err := readRecords(filepath.Join(dir, snapshotFile), false, func(r record) error {
+		if r.Op == opSnapshot {
+			gen = r.Gen
+			return nil
+		}
+		return q.apply(r)
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+	err = readRecords(filepath.Join(dir, walFile(gen)), true, q.apply)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("failed to replay log: %w", err)
+	}
+	// logs of older generations are leftovers of a snapshot interrupted by a crash
+	stale, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	for _, path := range stale {
+		if filepath.Base(path) != walFile(gen) {
+			os.Remove(path)
+		}
+	}
+	return gen, nil
+}
+
+func (q *queue[K, V]) apply(r record) error {
+	var k K
+	if err := json.Unmarshal(r.Key, &k); err != nil {
+		return fmt.Errorf("invalid key in %s record: %w", r.Op, err)
+	}
+	var content V
+	if r.Op == opAdd || r.Op == opSet {
+		var err error
+		if content, err = q.codec.Unmarshal(r.Value); err != nil {
+			return fmt.Errorf("invalid value of %v: %w", k, err)
+		}
+	}
+	switch r.Op {
+	case opAdd:
+		q.add(&Item[K, V]{key: k, content: content, priority: r.Priority, attempts: r.Attempts, addedAt: r.AddedAt, notBefore: r.NotBefore, expiresAt: r.ExpiresAt, lastErr: r.Error})
+	case opSet:
+		if v, ok := q.dict[k]; ok {
+			v.content = content
+		}
+	case opDel:
+		q.remove(k)
+	case opDone:
+		q.remove(k)
+		q.done.put(k, struct{}{}, r.DoneAt)
+	case opFail:
+		if v, ok := q.dict[k]; ok {
+			v.lastErr = r.Error
+		}
+	case opResult:
+		q.results.put(k, Status{State: r.State, Attempts: r.Attempts, LastError: r.Error, Result: r.Result, DoneAt: r.DoneAt}, r.DoneAt)
+	case opAttempt:
+		if v, ok := q.dict[k]; ok {
+			v.attempts++
+		}
+	case opRequeue:
+		if v, ok := q.dict[k]; ok && v.lease == nil && !v.delayed {
+			q.items.remove(v)
+			q.requeue(v)
+		}
+	case opLease:
+		if v, ok := q.dict[k]; ok && v.lease == nil && !v.delayed {
+			q.items.remove(v)
+			q.hold(&Lease[K, V]{id: r.Lease, item: v, snap: v.snapshot(), expiresAt: r.ExpiresAt})
+		}
+	default:
+		return fmt.Errorf("unknown record op %q", r.Op)
+	}
+	return nil
+}
+
+// readRecords calls fn on every record in path. With tolerateTorn a record cut short
+// by a crash at the end of the file is dropped, and the file truncated past it.
+func readRecords(path string, tolerateTorn bool, fn func(record) error) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			return nil
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		var rec record
+		if jerr := json.Unmarshal(bytes.TrimSpace(line), &rec); jerr != nil || err == io.EOF {
+			if tolerateTorn && err == io.EOF {
+				return f.Truncate(offset)
+			}
+			return fmt.Errorf("corrupt record at offset %d of %s", offset, path)
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+		offset += int64(len(line))
+	}
+}
+
+func (j *journal) stop() {
+	j.once.Do(func() { close(j.done) })
+	j.wg.Wait()
+}
+
+// closeJournal stops the background loops, snapshots and closes the log, reporting any write error on the way
+func (q *queue[K, V]) closeJournal(j *journal) error {
+	// the loops take the queue lock so they have to be stopped before holding it
+	j.stop()
+
+	q.mux.Lock()
+	defer q.mux.Unlock()
+	if q.journal == nil {
+		return nil
+	}
+	err := q.snapshot()
+	if cerr := j.f.Close(); err == nil {
+		err = cerr
+	}
+	q.journal = nil
+	return err
+}
@@ -0,0 +1,166 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func keysOfQueue(q Queue[string, any]) []string {
+	keys := []string{}
+	for _, v := range q.List() {
+		keys = append(keys, v.key)
+	}
+	return keys
+}
+
+func TestDurableReplay(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	q.Add("foo", "bar")
+	q.Add("john", "doe", WithPriority(2))
+	q.Add("fizz", "bazz")
+	q.Add("biz", "bam")
+	q.Add("biz", "boom", WithUpsert())
+	q.Remove("fizz")
+	q.Pop()
+	l := mustLease(t, q, time.Minute)
+	assert.Equal(t, l.Item().key, "john")
+	// simulate a crash by dropping the queue without closing it
+	q.(*queue[string, any]).journal.stop()
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	defer q.Close()
+	// the lease is lost with the process so john is handed out again, remembering the attempt
+	assert.Equal(t, keysOfQueue(q), []string{"john", "biz"})
+	john := q.Get("john")
+	assert.Equal(t, john.Attempts(), 1)
+	assert.Equal(t, john.Priority(), 2)
+	_, v := john.KeyValue()
+	assert.Equal(t, v, "doe")
+	_, v = q.Get("biz").KeyValue()
+	assert.Equal(t, v, "boom")
+}
+
+func TestDurableRequeue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	require.NoError(t, q.Nack(mustLease(t, q, time.Minute).ID(), WithError("boom")))
+	q.(*queue[string, any]).journal.stop()
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	defer q.Close()
+	// foo went back to the end of the queue when it was nacked
+	assert.Equal(t, keysOfQueue(q), []string{"john", "foo"})
+	st, _ := q.Status("foo")
+	assert.Equal(t, st.Attempts, 1)
+	assert.Equal(t, st.LastError, "boom")
+}
+
+func TestDurableWriteFailure(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	require.NoError(t, q.Add("foo", "bar"))
+	q.(*queue[string, any]).journal.f.Close()
+
+	assert.ErrorIs(t, q.Add("john", "doe"), ErrNotDurable)
+	// the queue refuses changes once one of them was not persisted
+	assert.ErrorIs(t, q.Add("fizz", "bazz"), ErrNotDurable)
+	_, err = q.Lease(time.Minute)
+	assert.ErrorIs(t, err, ErrNotDurable)
+	_, err = q.PopN(1)
+	assert.ErrorIs(t, err, ErrNotDurable)
+	assert.Nil(t, q.Pop())
+	_, err = q.Remove("foo")
+	assert.ErrorIs(t, err, ErrNotDurable)
+	assert.Error(t, q.Close())
+}
+
+func TestDurableSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncBatch, SyncInterval: time.Millisecond, SnapshotInterval: 10 * time.Millisecond}, JSONCodec[any]{}, WithOrder(LIFO))
+	require.NoError(t, err)
+	q.Add("foo", "bar")
+	q.Add("john", "doe")
+	dq := q.(*queue[string, any])
+	// later snapshots rotate the log again, so wait for any of them rather than a given log file
+	assert.Eventually(t, func() bool {
+		dq.mux.Lock()
+		defer dq.mux.Unlock()
+		return dq.journal.gen >= 1
+	}, time.Second, 5*time.Millisecond)
+	q.Add("fizz", "bazz")
+	q.Remove("foo")
+	require.NoError(t, q.Close())
+
+	logs, _ := filepath.Glob(filepath.Join(dir, "wal-*.log"))
+	assert.Len(t, logs, 1)
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncNone}, JSONCodec[any]{}, WithOrder(LIFO))
+	require.NoError(t, err)
+	defer q.Close()
+	assert.Equal(t, keysOfQueue(q), []string{"fizz", "john"})
+}
+
+func TestDurableTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	q, err := OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	q.Add("foo", "bar")
+	q.(*queue[string, any]).journal.stop()
+
+	f, err := os.OpenFile(filepath.Join(dir, walFile(0)), os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	f.WriteString(`{"op":"add","key":"tor`)
+	f.Close()
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	q.Add("john", "doe")
+	q.(*queue[string, any]).journal.stop()
+
+	q, err = OpenDurable[string, any](dir, Durability{Sync: SyncAlways}, JSONCodec[any]{})
+	require.NoError(t, err)
+	defer q.Close()
+	assert.Equal(t, keysOfQueue(q), []string{"foo", "john"})
+}
+
+func TestParseSyncPolicy(t *testing.T) {
+	for _, p := range []SyncPolicy{SyncAlways, SyncBatch, SyncNone} {
+		parsed, err := ParseSyncPolicy(p.String())
+		assert.NoError(t, err)
+		assert.Equal(t, parsed, p)
+	}
+	_, err := ParseSyncPolicy("sometimes")
+	assert.Error(t, err)
+}
+
+func TestDurableTypedValues(t *testing.T) {
+	type job struct {
+		URL     string
+		Retries int
+	}
+	dir := t.TempDir()
+	q, err := OpenDurable[int, job](dir, Durability{Sync: SyncNone}, JSONCodec[job]{})
+	require.NoError(t, err)
+	require.NoError(t, q.Add(1, job{URL: "http://foo", Retries: 2}))
+	require.NoError(t, q.Add(2, job{URL: "http://bar"}))
+	require.NoError(t, q.Close())
+
+	q, err = OpenDurable[int, job](dir, Durability{Sync: SyncNone}, JSONCodec[job]{})
+	require.NoError(t, err)
+	defer q.Close()
+	k, v := q.Pop().KeyValue()
+	assert.Equal(t, k, 1)
+	assert.Equal(t, v, job{URL: "http://foo", Retries: 2})
+}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/worker"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// watchRetry is how long watch waits before resuming a stream that dropped
+var watchRetry = time.Second
+
+var commands = map[string]command{
+	"add":          {args: "<name> <value>", help: "add a task", run: add},
+	"add-batch":    {args: "< tasks.jsonl", help: "add the tasks read from stdin, one json task per line", run: addBatch},
+	"watch":        {help: "stream the tasks made available in or expired from a queue, resuming when the stream drops", streaming: true, run: watch},
+	"pop":          {help: "remove tasks from the head of a queue", run: pop},
+	"peek":         {help: "list tasks from the head of a queue, leaving them queued", run: peek},
+	"remove":       {args: "<name>", help: "remove a task whether it is queued, delayed or leased", run: remove},
+	"get":          {args: "<name>", help: "look up where a task is in its lifecycle", run: get},
+	"lease":        {help: "lease the next task of a queue", run: lease},
+	"ack":          {args: "<lease id>", help: "settle a lease as done", run: ack},
+	"nack":         {args: "<lease id>", help: "give up a lease, the task is redelivered", run: nack},
+	"work":         {args: "<name>...", help: "handle the tasks of the given names by printing them until interrupted", streaming: true, run: work},
+	"list-queues":  {help: "list the queues of the server", run: listQueues},
+	"stats":        {args: "<queue>", help: "show the stats of a queue", run: stats},
+	"create-queue": {args: "<queue>", help: "create a queue", run: createQueue},
+	"delete-queue": {args: "<queue>", help: "delete a queue with every task in it", run: deleteQueue},
+	"dlq":          {args: "list|get|replay [flags] [names]", help: "inspect and replay the dead letters of a queue", run: dlq},
+	"drain":        {help: "ready the server for a restart", run: drain},
+}
+
+// parse parses args with fs and checks the number of arguments left is within [min, max], max < 0 for no limit
+func parse(fs *flag.FlagSet, args []string, min, max int) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < min || max >= 0 && fs.NArg() > max {
+		fs.Usage()
+		return errUsage
+	}
+	return nil
+}
+
+// queueFlags adds the flags naming the queue, and consumer group, a command works on
+func queueFlags(fs *flag.FlagSet) (*string, *string) {
+	return fs.String("q", "q1", "queue"), fs.String("group", "", "consumer group, required for queues with consumer groups")
+}
+
+func add(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid := fs.String("q", "q1", "queue")
+	kind := fs.String("kind", "text", "how the value is sent, one of [text, data, json], json values must be objects")
+	prio := fs.Int("priority", 0, "task priority, higher is handed out first by priority queues")
+	delay := fs.Duration("delay", 0, "hold the task back for this long")
+	notBefore := fs.String("not-before", "", "hold the task back until this RFC 3339 time")
+	ttl := fs.Duration("ttl", 0, "discard the task if it is not handed out within this long")
+	expiresAt := fs.String("expires-at", "", "discard the task if it is not handed out by this RFC 3339 time")
+	upsert := fs.Bool("upsert", false, "replace the value of a task already present under the name")
+	if err := parse(fs, args, 2, 2); err != nil {
+		return err
+	}
+	p, err := payload(*kind, fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("invalid task value: %w", err)
+	}
+	t := &pb.Task{Queue: *qid, Name: fs.Arg(0), Payload: p, Priority: int32(*prio)}
+	if *delay > 0 {
+		t.Delay = durationpb.New(*delay)
+	}
+	if *notBefore != "" {
+		at, err := time.Parse(time.RFC3339, *notBefore)
+		if err != nil {
+			return fmt.Errorf("invalid -not-before: %w", err)
+		}
+		t.NotBefore = timestamppb.New(at)
+	}
+	if *ttl > 0 {
+		t.Ttl = durationpb.New(*ttl)
+	}
+	if *expiresAt != "" {
+		at, err := time.Parse(time.RFC3339, *expiresAt)
+		if err != nil {
+			return fmt.Errorf("invalid -expires-at: %w", err)
+		}
+		t.ExpiresAt = timestamppb.New(at)
+	}
+	r, err := c.cli.AddTask(c.ctx, &pb.TaskAddRequest{Task: t, Upsert: *upsert})
+	if err != nil {
+		return err
+	}
+	return c.out.addResults(r, []*pb.TaskAddResult{{Queue: t.GetQueue(), Name: t.GetName(), Msg: r.GetMsg(), Duplicate: r.GetDuplicate(), Original: r.GetOriginal()}})
+}
+
+// payload builds a task payload of the given kind out of v
+func payload(kind, v string) (*pb.Payload, error) {
+	switch kind {
+	case "text":
+		return &pb.Payload{Kind: &pb.Payload_Text{Text: v}}, nil
+	case "data":
+		return &pb.Payload{Kind: &pb.Payload_Data{Data: []byte(v)}}, nil
+	case "json":
+		s := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(v), s); err != nil {
+			return nil, err
+		}
+		return &pb.Payload{Kind: &pb.Payload_Json{Json: s}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported payload kind %s", kind)
+	}
+}
+
+func addBatch(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid := fs.String("q", "q1", "queue of the tasks that do not name one")
+	upsert := fs.Bool("upsert", false, "replace the value of tasks already present under their name")
+	size := fs.Int("batch-size", 100, "how many tasks are sent per rpc")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if *size <= 0 {
+		return fmt.Errorf("-batch-size must be positive, got %d", *size)
+	}
+	// tasks are read as the json mapping of the Task message, such as
+	// {"name": "a", "priority": 2, "delay": "10s", "payload": {"json": {"to": "x@example.com"}}}
+	sc := bufio.NewScanner(c.in)
+	sc.Buffer(nil, 4<<20)
+	reply := &pb.TasksAddReply{}
+	batch := []*pb.Task{}
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		r, err := c.cli.AddTasks(c.ctx, &pb.TasksAddRequest{Tasks: batch, Upsert: *upsert})
+		if err != nil {
+			return err
+		}
+		reply.Results = append(reply.Results, r.GetResults()...)
+		batch = nil
+		return nil
+	}
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		t := &pb.Task{}
+		if err := protojson.Unmarshal(sc.Bytes(), t); err != nil {
+			return fmt.Errorf("invalid task on line %d: %w", line, err)
+		}
+		if t.GetQueue() == "" {
+			t.Queue = *qid
+		}
+		batch = append(batch, t)
+		if len(batch) == *size {
+			if err := send(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("failed to read tasks: %w", err)
+	}
+	if err := send(); err != nil {
+		return err
+	}
+	if err := c.out.addResults(reply, reply.GetResults()); err != nil {
+		return err
+	}
+	failed := 0
+	for _, r := range reply.GetResults() {
+		if codes.Code(r.GetCode()) != codes.OK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", failed, len(reply.GetResults()))
+	}
+	return nil
+}
+
+func watch(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	from := fs.Uint64("from", 0, "sequence number of the first event, one past the last one received to resume an earlier watch")
+	logID := fs.String("log", "", "log id of the events received by the earlier watch, required with -from")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	first := true
+	for {
+		s, err := c.cli.WatchQueue(c.ctx, &pb.TaskWatchRequest{Queue: *qid, Group: *group, FromSequence: *from, LogId: *logID})
+		for err == nil {
+			var r *pb.TaskWatchReply
+			if r, err = s.Recv(); err != nil {
+				break
+			}
+			if err := c.out.event(r, first); err != nil {
+				return err
+			}
+			first = false
+			*from, *logID = r.GetSequence()+1, r.GetLogId()
+		}
+		if c.ctx.Err() != nil {
+			return nil
+		}
+		// the server went away or is draining, the watch picks up where it dropped once it is back
+		if status.Code(err) != codes.Unavailable {
+			return err
+		}
+		log.Printf("watch on queue %s dropped, resuming from event %d: %s", *qid, *from, err)
+		select {
+		case <-time.After(watchRetry):
+		case <-c.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func pop(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	n := fs.Int("n", 1, "how many tasks to pop at most")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	r, err := c.cli.PopTasks(c.ctx, &pb.TasksPopRequest{Queue: *qid, Group: *group, MaxN: int32(*n)})
+	if err != nil {
+		return err
+	}
+	return c.out.tasks(r, r.GetTasks())
+}
+
+func peek(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	n := fs.Int("n", 10, "how many tasks to list at most")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	r, err := c.cli.PeekTasks(c.ctx, &pb.TasksPeekRequest{Queue: *qid, Group: *group, MaxN: int32(*n)})
+	if err != nil {
+		return err
+	}
+	return c.out.tasks(r, r.GetTasks())
+}
+
+func remove(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid := fs.String("q", "q1", "queue")
+	group := fs.String("group", "", "consumer group to remove the task from, every group when empty")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.RemoveTask(c.ctx, &pb.TaskRemoveRequest{Queue: *qid, Group: *group, Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.out.tasks(r, []*pb.Task{r.GetTask()})
+}
+
+func get(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.GetTask(c.ctx, &pb.TaskGetRequest{Queue: *qid, Group: *group, Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.out.taskState(fs.Arg(0), r)
+}
+
+func lease(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	d := fs.Duration("lease", 30*time.Second, "how long the task stays invisible to other workers")
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	r, err := c.cli.LeaseTask(c.ctx, &pb.TaskLeaseRequest{Queue: *qid, Group: *group, LeaseDuration: durationpb.New(*d)})
+	if err != nil {
+		return err
+	}
+	return c.out.lease(r)
+}
+
+func ack(c *cmdContext, args []string) error {
+	fs := c.flags()
+	result := fs.String("result", "", "outcome of the task, handed back by get")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.AckTask(c.ctx, &pb.TaskAckRequest{LeaseId: fs.Arg(0), Result: []byte(*result)})
+	if err != nil {
+		return err
+	}
+	return c.out.message(r, r.GetMsg())
+}
+
+func nack(c *cmdContext, args []string) error {
+	fs := c.flags()
+	reason := fs.String("error", "", "why the task failed, handed back by get")
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.NackTask(c.ctx, &pb.TaskNackRequest{LeaseId: fs.Arg(0), Error: *reason})
+	if err != nil {
+		return err
+	}
+	return c.out.message(r, r.GetMsg())
+}
+
+func work(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	conc := fs.Int("concurrency", 1, "how many tasks are handled at once")
+	d := fs.Duration("lease", 30*time.Second, "how long a leased task stays invisible to other workers")
+	if err := parse(fs, args, 1, -1); err != nil {
+		return err
+	}
+	w := worker.New(c.cli, *qid, worker.WithGroup(*group), worker.WithConcurrency(*conc), worker.WithLeaseDuration(*d))
+	for _, name := range fs.Args() {
+		w.Handle(name, func(ctx context.Context, t *pb.Task) ([]byte, error) {
+			return nil, c.out.tasks(t, []*pb.Task{t})
+		})
+	}
+	log.Printf("working on tasks named %v in queue %s", fs.Args(), *qid)
+	return w.Run(c.ctx)
+}
+
+func listQueues(c *cmdContext, args []string) error {
+	if err := parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+	r, err := c.cli.ListQueues(c.ctx, &pb.QueueListRequest{})
+	if err != nil {
+		return err
+	}
+	return c.out.queues(r)
+}
+
+func stats(c *cmdContext, args []string) error {
+	fs := c.flags()
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.GetQueueStats(c.ctx, &pb.QueueStatsRequest{Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.out.stats(fs.Arg(0), r)
+}
+
+func createQueue(c *cmdContext, args []string) error {
+	fs := c.flags()
+	conf := fs.String("config", "{}", `json mapping of the QueueConfig message, such as {"order": "PRIORITY", "max_attempts": 3}`)
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	qc := &pb.QueueConfig{}
+	if err := protojson.Unmarshal([]byte(*conf), qc); err != nil {
+		return fmt.Errorf("invalid -config: %w", err)
+	}
+	r, err := c.cli.CreateQueue(c.ctx, &pb.QueueCreateRequest{Name: fs.Arg(0), Config: qc})
+	if err != nil {
+		return err
+	}
+	return c.out.queues(&pb.QueueListReply{Queues: []*pb.QueueInfo{r.GetQueue()}})
+}
+
+func deleteQueue(c *cmdContext, args []string) error {
+	fs := c.flags()
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.DeleteQueue(c.ctx, &pb.QueueDeleteRequest{Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.out.message(r, fmt.Sprintf("queue %s deleted", fs.Arg(0)))
+}
+
+// dlq dispatches to its own subcommands
+func dlq(c *cmdContext, args []string) error {
+	if len(args) == 0 {
+		c.flags().Usage()
+		return errUsage
+	}
+	sub, ok := dlqCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown dlq command %s, one of [list, get, replay]", args[0])
+	}
+	c.name, c.args = "dlq "+args[0], sub.args
+	return sub.run(c, args[1:])
+}
+
+var dlqCommands = map[string]command{
+	"list":   {run: dlqList},
+	"get":    {args: "<name>", run: dlqGet},
+	"replay": {args: "[name...]", run: dlqReplay},
+}
+
+func dlqList(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	if err := parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	r, err := c.cli.ListDeadLetters(c.ctx, &pb.DeadLetterListRequest{Queue: *qid, Group: *group})
+	if err != nil {
+		return err
+	}
+	return c.out.tasks(r, r.GetTasks())
+}
+
+func dlqGet(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	if err := parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+	r, err := c.cli.GetDeadLetter(c.ctx, &pb.DeadLetterGetRequest{Queue: *qid, Group: *group, Name: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.out.tasks(r, []*pb.Task{r.GetTask()})
+}
+
+func dlqReplay(c *cmdContext, args []string) error {
+	fs := c.flags()
+	qid, group := queueFlags(fs)
+	if err := parse(fs, args, 0, -1); err != nil {
+		return err
+	}
+	r, err := c.cli.ReplayDeadLetters(c.ctx, &pb.DeadLetterReplayRequest{Queue: *qid, Group: *group, Names: fs.Args()})
+	if err != nil {
+		return err
+	}
+	return c.out.names(r, "REPLAYED", r.GetNames())
+}
+
+func drain(c *cmdContext, args []string) error {
+	if err := parse(c.flags(), args, 0, 0); err != nil {
+		return err
+	}
+	r, err := c.cli.Drain(c.ctx, &pb.DrainRequest{})
+	if err != nil {
+		return err
+	}
+	return c.out.message(r, fmt.Sprintf("server draining, %d tasks queued and %d in flight left", r.GetDepth(), r.GetInFlight()))
+}
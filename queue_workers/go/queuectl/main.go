@@ -0,0 +1,169 @@
+// queuectl is the command line tool for the Queue service, run it with -h for the commands
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	pb "queue-workers/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+var (
+	addr       = flag.String("addr", "localhost:8000", "address of the queue server")
+	useTLS     = flag.Bool("tls", false, "connect over tls, implied by -ca and -cert")
+	caFile     = flag.String("ca", "", "ca to verify the server certificate with instead of the system roots")
+	certFile   = flag.String("cert", "", "client certificate to authenticate with")
+	keyFile    = flag.String("key", "", "private key of -cert")
+	serverName = flag.String("server-name", "", "name to verify the server certificate against, defaults to the host of -addr")
+	token      = flag.String("token", os.Getenv("QUEUECTL_TOKEN"), "bearer token to authenticate with, defaults to $QUEUECTL_TOKEN")
+	timeout    = flag.Duration("timeout", 30*time.Second, "how long a command is given to complete, watch and work run until interrupted")
+	output     = flag.String("o", "table", "output format, one of [table, json], json prints one object per line")
+)
+
+// command is a subcommand of queuectl
+type command struct {
+	// arguments taken after the flags of the command
+	args string
+	help string
+	// streaming commands run until interrupted instead of -timeout
+	streaming bool
+	run       func(c *cmdContext, args []string) error
+}
+
+// cmdContext is what commands run with
+type cmdContext struct {
+	ctx context.Context
+	cli pb.QueueClient
+	in  io.Reader
+	out *printer
+	// name of the command, the dlq subcommands included, and the arguments it takes after its flags
+	name string
+	args string
+}
+
+// flags returns the flag set of the command, usage errors are returned by Parse
+func (c *cmdContext) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: queuectl %s [flags] %s\n", c.name, c.args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "usage: queuectl [flags] <command> [command flags] [args]")
+	fmt.Fprintln(out, "\ncommands:")
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-14s %s\n", name, commands[name].help)
+	}
+	fmt.Fprintln(out, "\nflags:")
+	flag.PrintDefaults()
+}
+
+// dial connects to the queue server as told by the flags
+func dial() (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if *useTLS || *caFile != "" || *certFile != "" {
+		c := &tls.Config{ServerName: *serverName, MinVersion: tls.VersionTLS12}
+		if *caFile != "" {
+			pem, err := os.ReadFile(*caFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ca: %w", err)
+			}
+			c.RootCAs = x509.NewCertPool()
+			if !c.RootCAs.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificate found in ca %s", *caFile)
+			}
+		}
+		if *certFile != "" {
+			cert, err := tls.LoadX509KeyPair(*certFile, *keyFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load client certificate: %w", err)
+			}
+			c.Certificates = []tls.Certificate{cert}
+		}
+		creds = credentials.NewTLS(c)
+	}
+	return grpc.Dial(*addr, grpc.WithTransportCredentials(creds))
+}
+
+// outgoing adds the credentials of the flags to the calls made with ctx
+func outgoing(ctx context.Context) context.Context {
+	if *token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+*token)
+	}
+	return ctx
+}
+
+// run runs the command named by args[0] with the rest of args
+func run(ctx context.Context, cli pb.QueueClient, in io.Reader, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown command %s, see queuectl -h", args[0])
+	}
+	return cmd.run(&cmdContext{ctx: ctx, cli: cli, in: in, out: out, name: args[0], args: cmd.args}, args[1:])
+}
+
+// errUsage is returned when queuectl is run the wrong way, usage has been printed then
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("queuectl: ")
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	out, err := newPrinter(os.Stdout, *output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	conn, err := dial()
+	if err != nil {
+		log.Fatalf("failed to set up connection: %s", err)
+	}
+	defer conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if cmd, ok := commands[flag.Arg(0)]; ok && !cmd.streaming {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+	err = run(outgoing(ctx), pb.NewQueueClient(conn), os.Stdin, out, flag.Args())
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
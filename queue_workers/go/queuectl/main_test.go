@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	pb "queue-workers/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// fakeServer records the batches added and serves a watch dropping once after two events
+type fakeServer struct {
+	pb.UnimplementedQueueServer
+	mux     sync.Mutex
+	batches [][]*pb.Task
+	watches []*pb.TaskWatchRequest
+}
+
+func (s *fakeServer) AddTasks(ctx context.Context, r *pb.TasksAddRequest) (*pb.TasksAddReply, error) {
+	s.mux.Lock()
+	s.batches = append(s.batches, r.GetTasks())
+	s.mux.Unlock()
+	results := []*pb.TaskAddResult{}
+	for _, t := range r.GetTasks() {
+		res := &pb.TaskAddResult{Queue: t.GetQueue(), Name: t.GetName(), Msg: "task added"}
+		if t.GetName() == "bad" {
+			res.Code, res.Msg = int32(codes.InvalidArgument), "bad task"
+		}
+		results = append(results, res)
+	}
+	return &pb.TasksAddReply{Results: results}, nil
+}
+
+func (s *fakeServer) PopTasks(ctx context.Context, r *pb.TasksPopRequest) (*pb.TasksPopReply, error) {
+	return &pb.TasksPopReply{Tasks: []*pb.Task{{Queue: r.GetQueue(), Name: "a", Priority: 2, Payload: &pb.Payload{Kind: &pb.Payload_Text{Text: "hello"}}}}}, nil
+}
+
+func (s *fakeServer) WatchQueue(r *pb.TaskWatchRequest, w pb.Queue_WatchQueueServer) error {
+	s.mux.Lock()
+	s.watches = append(s.watches, r)
+	first := len(s.watches) == 1
+	s.mux.Unlock()
+	seq := r.GetFromSequence()
+	if first {
+		seq = 1
+	}
+	for i := 0; i < 2; i++ {
+		name := string(rune('a' + seq - 1))
+		if err := w.Send(&pb.TaskWatchReply{Task: &pb.Task{Name: name}, Event: pb.TaskEvent_ADDED, Sequence: seq, LogId: "log"}); err != nil {
+			return err
+		}
+		seq++
+	}
+	if first {
+		return status.Error(codes.Unavailable, "server is draining")
+	}
+	<-w.Context().Done()
+	return nil
+}
+
+func startFake(t *testing.T) (*fakeServer, pb.QueueClient) {
+	t.Helper()
+	fake := &fakeServer{}
+	lis := bufconn.Listen(1 << 20)
+	grpcServ := grpc.NewServer()
+	pb.RegisterQueueServer(grpcServ, fake)
+	go grpcServ.Serve(lis)
+	t.Cleanup(grpcServ.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return fake, pb.NewQueueClient(conn)
+}
+
+// syncBuffer is a bytes.Buffer safe to read while a command writes to it
+type syncBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.String()
+}
+
+func TestAddBatch(t *testing.T) {
+	fake, cli := startFake(t)
+	var out bytes.Buffer
+	in := strings.NewReader(`{"name": "a", "payload": {"text": "x"}}
+
+{"name": "bad", "queue": "q2"}
+{"name": "c", "delay": "10s"}
+`)
+	err := run(context.Background(), cli, in, &printer{w: &out, json: true}, []string{"add-batch", "-q", "jobs", "-batch-size", "2"})
+	assert.EqualError(t, err, "1 of 3 tasks failed")
+
+	require.Len(t, fake.batches, 2)
+	assert.Len(t, fake.batches[0], 2)
+	queues := []string{}
+	for _, b := range fake.batches {
+		for _, t := range b {
+			queues = append(queues, t.GetQueue())
+		}
+	}
+	assert.Equal(t, queues, []string{"jobs", "q2", "jobs"})
+	assert.Equal(t, fake.batches[1][0].GetDelay().AsDuration(), 10*time.Second)
+	reply := &pb.TasksAddReply{}
+	require.NoError(t, protojson.Unmarshal(out.Bytes(), reply))
+	require.Len(t, reply.GetResults(), 3)
+	assert.Equal(t, codes.Code(reply.GetResults()[1].GetCode()), codes.InvalidArgument)
+
+	err = run(context.Background(), cli, strings.NewReader("{not json"), &printer{w: &out}, []string{"add-batch"})
+	assert.ErrorContains(t, err, "invalid task on line 1")
+}
+
+func TestTableOutput(t *testing.T) {
+	_, cli := startFake(t)
+	var out bytes.Buffer
+	require.NoError(t, run(context.Background(), cli, nil, &printer{w: &out}, []string{"pop", "-q", "jobs", "-n", "5"}))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Equal(t, strings.Fields(lines[0]), []string{"QUEUE", "NAME", "PRIORITY", "ATTEMPTS", "NOT_BEFORE", "PAYLOAD"})
+	assert.Equal(t, strings.Fields(lines[1]), []string{"jobs", "a", "2", "0", "-", "hello"})
+
+	assert.ErrorIs(t, run(context.Background(), cli, nil, &printer{w: &out}, []string{"stats"}), errUsage)
+	assert.ErrorContains(t, run(context.Background(), cli, nil, &printer{w: &out}, []string{"nope"}), "unknown command")
+}
+
+func TestWatchResumes(t *testing.T) {
+	watchRetry = 10 * time.Millisecond
+	fake, cli := startFake(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &syncBuffer{}
+	done := make(chan error)
+	go func() { done <- run(ctx, cli, nil, &printer{w: out, json: true}, []string{"watch", "-q", "jobs"}) }()
+
+	// the watch dropping after two events is resumed from the third
+	assert.Eventually(t, func() bool { return strings.Count(out.String(), "\n") == 4 }, time.Second, 5*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+	fake.mux.Lock()
+	defer fake.mux.Unlock()
+	require.Len(t, fake.watches, 2)
+	assert.Equal(t, fake.watches[1].GetFromSequence(), uint64(3))
+	assert.Equal(t, fake.watches[1].GetLogId(), "log")
+	names := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		r := &pb.TaskWatchReply{}
+		require.NoError(t, protojson.Unmarshal([]byte(line), r))
+		names = append(names, r.GetTask().GetName())
+	}
+	assert.Equal(t, names, []string{"a", "b", "c", "d"})
+}
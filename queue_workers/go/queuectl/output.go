@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	pb "queue-workers/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// printer writes replies as tables meant for people or as json objects, one per line, meant for scripts
+type printer struct {
+	// held while printing, work prints from several goroutines
+	mux  sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case "table":
+		return &printer{w: w}, nil
+	case "json":
+		return &printer{w: w, json: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported output format %s, one of [table, json]", format)
+	}
+}
+
+// print writes m as json, or as the table of header and rows
+func (p *printer) print(m proto.Message, header []string, rows [][]string) error {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	if p.json {
+		b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+var taskHeader = []string{"QUEUE", "NAME", "PRIORITY", "ATTEMPTS", "NOT_BEFORE", "PAYLOAD"}
+
+func taskRow(t *pb.Task) []string {
+	return []string{t.GetQueue(), t.GetName(), fmt.Sprint(t.GetPriority()), fmt.Sprint(t.GetAttempts()), timestamp(t.GetNotBefore()), payloadText(t.GetPayload())}
+}
+
+// tasks prints m, a reply holding tasks
+func (p *printer) tasks(m proto.Message, tasks []*pb.Task) error {
+	rows := [][]string{}
+	for _, t := range tasks {
+		rows = append(rows, taskRow(t))
+	}
+	return p.print(m, taskHeader, rows)
+}
+
+func (p *printer) addResults(m proto.Message, results []*pb.TaskAddResult) error {
+	rows := [][]string{}
+	for _, r := range results {
+		rows = append(rows, []string{r.GetQueue(), r.GetName(), codes.Code(r.GetCode()).String(), fmt.Sprint(r.GetDuplicate()), r.GetMsg()})
+	}
+	return p.print(m, []string{"QUEUE", "NAME", "CODE", "DUPLICATE", "MESSAGE"}, rows)
+}
+
+// event prints a watch event, the header is left out so events line up under the first one
+func (p *printer) event(r *pb.TaskWatchReply, first bool) error {
+	var header []string
+	if first {
+		header = append([]string{"SEQUENCE", "EVENT"}, taskHeader...)
+	}
+	row := append([]string{fmt.Sprint(r.GetSequence()), r.GetEvent().String()}, taskRow(r.GetTask())...)
+	return p.print(r, header, [][]string{row})
+}
+
+func (p *printer) queues(r *pb.QueueListReply) error {
+	rows := [][]string{}
+	for _, q := range r.GetQueues() {
+		c := q.GetConfig()
+		rows = append(rows, []string{
+			q.GetName(), c.GetOrder().String(), fmt.Sprint(c.GetMaxAttempts()), fmt.Sprint(c.GetMaxSize()),
+			c.GetOverflow().String(), orNone(q.GetDeadLetterQueue()), orNone(strings.Join(c.GetConsumerGroups(), ",")),
+		})
+	}
+	return p.print(r, []string{"NAME", "ORDER", "MAX_ATTEMPTS", "MAX_SIZE", "OVERFLOW", "DEAD_LETTERS", "GROUPS"}, rows)
+}
+
+func (p *printer) stats(name string, r *pb.QueueStatsReply) error {
+	oldest := "-"
+	if r.GetOldestAge() != nil {
+		oldest = r.GetOldestAge().AsDuration().Round(time.Millisecond).String()
+	}
+	return p.print(r, []string{"QUEUE", "DEPTH", "IN_FLIGHT", "DELAYED", "OLDEST_AGE", "DROPPED", "REJECTED", "EXPIRED", "MAX_SIZE", "OVERFLOW"}, [][]string{{
+		name, fmt.Sprint(r.GetDepth()), fmt.Sprint(r.GetInFlight()), fmt.Sprint(r.GetDelayed()), oldest,
+		fmt.Sprint(r.GetDropped()), fmt.Sprint(r.GetRejected()), fmt.Sprint(r.GetExpired()), fmt.Sprint(r.GetMaxSize()), r.GetOverflow().String(),
+	}})
+}
+
+func (p *printer) taskState(name string, r *pb.TaskGetReply) error {
+	return p.print(r, []string{"NAME", "STATE", "ATTEMPTS", "DONE_AT", "LAST_ERROR", "RESULT"}, [][]string{{
+		name, r.GetState().String(), fmt.Sprint(r.GetAttempts()), timestamp(r.GetDoneAt()), orNone(r.GetLastError()), orNone(string(r.GetResult())),
+	}})
+}
+
+func (p *printer) lease(r *pb.TaskLeaseReply) error {
+	if r.GetTask() == nil {
+		return p.print(r, nil, [][]string{{"queue is empty"}})
+	}
+	return p.print(r, append([]string{"LEASE_ID", "EXPIRES_AT"}, taskHeader...), [][]string{
+		append([]string{r.GetLeaseId(), timestamp(r.GetExpiresAt())}, taskRow(r.GetTask())...),
+	})
+}
+
+// names prints the names held by m, one per line
+func (p *printer) names(m proto.Message, header string, names []string) error {
+	rows := [][]string{}
+	for _, n := range names {
+		rows = append(rows, []string{n})
+	}
+	return p.print(m, []string{header}, rows)
+}
+
+// message prints the human readable message of a reply
+func (p *printer) message(m proto.Message, msg string) error {
+	return p.print(m, nil, [][]string{{msg}})
+}
+
+// payloadText renders a payload on a single line, binary data base64 encoded
+func payloadText(pl *pb.Payload) string {
+	switch k := pl.GetKind().(type) {
+	case *pb.Payload_Text:
+		return k.Text
+	case *pb.Payload_Data:
+		return base64.StdEncoding.EncodeToString(k.Data)
+	case *pb.Payload_Json:
+		b, err := protojson.Marshal(k.Json)
+		if err != nil {
+			return "-"
+		}
+		return string(b)
+	case *pb.Payload_Proto:
+		return k.Proto.GetTypeUrl()
+	default:
+		return "-"
+	}
+}
+
+func timestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return "-"
+	}
+	return ts.AsTime().Local().Format(time.RFC3339)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func (s *server) CreateQueue(ctx context.Context, r *pb.QueueCreateRequest) (*pb.QueueCreateReply, error) {
+	create := s.queues.create
+	if s.cluster != nil {
+		create = s.cluster.createQueue
+	}
+	info, err := create(r.GetName(), r.GetConfig())
+	if err != nil {
+		return nil, err
+	}
+	return &pb.QueueCreateReply{Queue: info}, nil
+}
+
+func (s *server) DeleteQueue(ctx context.Context, r *pb.QueueDeleteRequest) (*pb.QueueDeleteReply, error) {
+	del := s.queues.delete
+	if s.cluster != nil {
+		del = s.cluster.deleteQueue
+	}
+	if err := del(r.GetName()); err != nil {
+		return nil, err
+	}
+	return &pb.QueueDeleteReply{Msg: fmt.Sprintf("queue %s deleted", r.GetName())}, nil
+}
+
+func (s *server) ListQueues(ctx context.Context, r *pb.QueueListRequest) (*pb.QueueListReply, error) {
+	return &pb.QueueListReply{Queues: s.queues.list()}, nil
+}
+
+func (s *server) GetQueueStats(ctx context.Context, r *pb.QueueStatsRequest) (*pb.QueueStatsReply, error) {
+	e, ok := s.queues.lookup(r.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, fmt.Sprintf("queue %s is not present", r.GetName()))
+	}
+	st := e.q.Stats()
+	// tasks are held by the consumer groups of a queue rather than the queue itself
+	for _, g := range e.groups {
+		if ge, ok := s.queues.lookup(groupQueueName(r.GetName(), g)); ok {
+			st = sumStats(st, ge.q.Stats())
+		}
+	}
+	reply := &pb.QueueStatsReply{
+		Depth:    int64(st.Depth),
+		InFlight: int64(st.InFlight),
+		Delayed:  int64(st.Delayed),
+		Dropped:  int64(st.Dropped),
+		Rejected: int64(st.Rejected),
+		MaxSize:  e.config.GetMaxSize(),
+		Overflow: e.config.GetOverflow(),
+		Expired:  int64(st.Expired),
+	}
+	if !st.Oldest.IsZero() {
+		reply.OldestAge = durationpb.New(time.Since(st.Oldest))
+	}
+	return reply, nil
+}
+
+// sumStats adds up the stats reported by two queues, the oldest task is the older of theirs
+func sumStats(a, b queue.Stats) queue.Stats {
+	a.Depth += b.Depth
+	a.InFlight += b.InFlight
+	a.Delayed += b.Delayed
+	a.Dropped += b.Dropped
+	a.Rejected += b.Rejected
+	a.Expired += b.Expired
+	if a.Oldest.IsZero() || !b.Oldest.IsZero() && b.Oldest.Before(a.Oldest) {
+		a.Oldest = b.Oldest
+	}
+	return a
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// op is what an identity is allowed to do to the queues an acl rule covers
+type op string
+
+const (
+	opProduce op = "produce"
+	opConsume op = "consume"
+	opAdmin   op = "admin"
+)
+
+// acl authenticates the clients of the queue server and authorizes their rpcs
+type acl struct {
+	// identities by bearer token
+	tokens map[string]string
+	rules  []aclRule
+}
+
+// aclConfig is the file an acl is read from, such as
+//
+//	{
+//	  "tokens": {"s3cr3t": "billing"},
+//	  "rules": [
+//	    {"identity": "billing", "queues": ["billing-*"], "ops": ["produce", "consume"]},
+//	    {"identity": "ops.example.com", "queues": ["*"], "ops": ["admin"]}
+//	  ]
+//	}
+//
+// Clients authenticate with a bearer token or a client certificate, whose common name is their identity.
+type aclConfig struct {
+	Tokens map[string]string `json:"tokens"`
+	Rules  []aclRule         `json:"rules"`
+}
+
+// aclRule allows an identity, every authenticated one for "*", ops on the queues matching the patterns.
+// Patterns are matched with path.Match, dead letter queues and the queues of consumer groups go by
+// the name of the queue they belong to.
+type aclRule struct {
+	Identity string   `json:"identity"`
+	Queues   []string `json:"queues"`
+	Ops      []op     `json:"ops"`
+}
+
+// loadACL reads the acl config at file
+func loadACL(file string) (*acl, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var c aclConfig
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid acl %s: %w", file, err)
+	}
+	return newACL(c)
+}
+
+func newACL(c aclConfig) (*acl, error) {
+	for token, id := range c.Tokens {
+		if token == "" || id == "" {
+			return nil, errors.New("acl tokens and identities must not be empty")
+		}
+	}
+	for i, r := range c.Rules {
+		if r.Identity == "" {
+			return nil, fmt.Errorf("acl rule %d has no identity", i)
+		}
+		for _, p := range r.Queues {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("acl rule %d has invalid queue pattern %q", i, p)
+			}
+		}
+		for _, o := range r.Ops {
+			if o != opProduce && o != opConsume && o != opAdmin {
+				return nil, fmt.Errorf("acl rule %d has unknown op %q, one of [produce, consume, admin]", i, o)
+			}
+		}
+	}
+	return &acl{tokens: c.Tokens, rules: c.Rules}, nil
+}
+
+// identity authenticates the client of ctx, by its bearer token when it sent one and by its
+// verified client certificate otherwise
+func (a *acl) identity(ctx context.Context) (string, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if auth := md.Get("authorization"); len(auth) > 0 {
+			token, ok := strings.CutPrefix(auth[0], "Bearer ")
+			id, known := a.tokens[token]
+			if len(auth) != 1 || !ok || !known {
+				return "", status.Errorf(codes.Unauthenticated, "invalid token")
+			}
+			return id, nil
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+			if cn := info.State.VerifiedChains[0][0].Subject.CommonName; cn != "" {
+				return cn, nil
+			}
+		}
+	}
+	return "", status.Errorf(codes.Unauthenticated, "missing bearer token or client certificate")
+}
+
+// identityKey is the context key the interceptors of an acl store the identity of the client under
+type identityKey struct{}
+
+// identityFrom returns the identity authenticated by the acl interceptors, false when the server has no acl
+func identityFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(identityKey{}).(string)
+	return id, ok
+}
+
+// allowed reports whether id may do o to the queue named name. An empty name stands for
+// the whole server, only rules covering every queue with "*" apply to it.
+func (a *acl) allowed(id string, o op, name string) bool {
+	// consumer groups and dead letter queues are covered by the rules of their queue
+	name, _, _ = strings.Cut(strings.TrimSuffix(name, queue.DeadLetterName("")), "@")
+	for _, r := range a.rules {
+		if r.Identity != id && r.Identity != "*" || !hasOp(r.Ops, o) {
+			continue
+		}
+		for _, p := range r.Queues {
+			if ok, _ := path.Match(p, name); ok && (name != "" || p == "*") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasOp(ops []op, o op) bool {
+	for _, v := range ops {
+		if v == o {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize checks id may make the rpc of req, any one of the ops returned by access is enough
+func (a *acl) authorize(id, method string, req any) error {
+	ops, names, ok := access(req)
+	if !ok {
+		// rpcs not known to the acl are refused until they are
+		return status.Errorf(codes.PermissionDenied, fmt.Sprintf("%s may not call %s", id, method))
+	}
+	for _, name := range names {
+		allowed := false
+		for _, o := range ops {
+			allowed = allowed || a.allowed(id, o, name)
+		}
+		if !allowed {
+			return status.Errorf(codes.PermissionDenied, fmt.Sprintf("%s may not call %s on queue %s", id, method, name))
+		}
+	}
+	return nil
+}
+
+// access returns the ops allowing the rpc of req and the queues it touches, false for rpcs the
+// acl does not know of. ListQueues touches none, its reply is filtered instead.
+func access(req any) ([]op, []string, bool) {
+	switch r := req.(type) {
+	case *pb.TaskAddRequest:
+		return []op{opProduce}, []string{r.GetTask().GetQueue()}, true
+	case *pb.TasksAddRequest:
+		names := []string{}
+		for _, t := range r.GetTasks() {
+			names = append(names, t.GetQueue())
+		}
+		return []op{opProduce}, names, true
+	case *pb.TaskRemoveRequest:
+		return []op{opProduce}, []string{r.GetQueue()}, true
+	case *pb.TasksPopRequest:
+		return []op{opConsume}, []string{r.GetQueue()}, true
+	case *pb.TasksPeekRequest:
+		return []op{opConsume}, []string{r.GetQueue()}, true
+	case *pb.TaskWatchRequest:
+		return []op{opConsume}, []string{r.GetQueue()}, true
+	case *pb.TaskLeaseRequest:
+		return []op{opConsume}, []string{r.GetQueue()}, true
+	case *pb.TaskAckRequest:
+		return []op{opConsume}, []string{leaseQueueName(r.GetLeaseId())}, true
+	case *pb.TaskNackRequest:
+		return []op{opConsume}, []string{leaseQueueName(r.GetLeaseId())}, true
+	case *pb.TaskGetRequest:
+		// producers follow up on the tasks they added
+		return []op{opProduce, opConsume}, []string{r.GetQueue()}, true
+	case *pb.DeadLetterListRequest:
+		return []op{opConsume}, []string{r.GetQueue()}, true
+	case *pb.DeadLetterGetRequest:
+		return []op{opConsume}, []string{r.GetQueue()}, true
+	case *pb.DeadLetterReplayRequest:
+		return []op{opAdmin}, []string{r.GetQueue()}, true
+	case *pb.QueueCreateRequest:
+		return []op{opAdmin}, []string{r.GetName()}, true
+	case *pb.QueueDeleteRequest:
+		return []op{opAdmin}, []string{r.GetName()}, true
+	case *pb.QueueStatsRequest:
+		return []op{opProduce, opConsume, opAdmin}, []string{r.GetName()}, true
+	case *pb.QueueListRequest:
+		return nil, nil, true
+	case *pb.DrainRequest:
+		return []op{opAdmin}, []string{""}, true
+	default:
+		return nil, nil, false
+	}
+}
+
+// leaseQueueName is the queue a lease id handed out by LeaseTask belongs to, as leaseQueue finds it
+func leaseQueueName(leaseID string) string {
+	name, _, _ := splitLeaseID(leaseID)
+	return name
+}
+
+// visible drops the queues id may do nothing to from a ListQueues reply
+func (a *acl) visible(id string, reply *pb.QueueListReply) *pb.QueueListReply {
+	infos := []*pb.QueueInfo{}
+	for _, info := range reply.GetQueues() {
+		name := info.GetName()
+		if a.allowed(id, opProduce, name) || a.allowed(id, opConsume, name) || a.allowed(id, opAdmin, name) {
+			infos = append(infos, info)
+		}
+	}
+	return &pb.QueueListReply{Queues: infos}
+}
+
+// unaryInterceptor refuses rpcs from clients that are not authenticated or not allowed to make them
+func (a *acl) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	id, err := a.identity(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.authorize(id, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	reply, err := handler(context.WithValue(ctx, identityKey{}, id), req)
+	if list, ok := reply.(*pb.QueueListReply); ok && err == nil {
+		return a.visible(id, list), nil
+	}
+	return reply, err
+}
+
+// streamInterceptor is unaryInterceptor for streams, the request of server streams is only
+// known once received so it is authorized then
+func (a *acl) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	id, err := a.identity(ss.Context())
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(ss.Context(), identityKey{}, id)
+	return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx, acl: a, id: id, method: info.FullMethod})
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	acl    *acl
+	id     string
+	method string
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.acl.authorize(s.id, s.method, m)
+}
+
+// serverTLS is the tls config of the grpc server, client certificates signed by clientCA are
+// verified when given and authenticate the client to the acl
+func serverTLS(certFile, keyFile, clientCA string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tls certificate: %w", err)
+	}
+	c := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if clientCA != "" {
+		pem, err := os.ReadFile(clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client ca: %w", err)
+		}
+		c.ClientCAs = x509.NewCertPool()
+		if !c.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in client ca %s", clientCA)
+		}
+		// clients authenticating with a bearer token do without a certificate
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return c, nil
+}
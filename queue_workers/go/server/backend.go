@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// configFile holds the config of a queue persisted on disk, queues are reopened from it on startup
+	configFile = "config.json"
+	// redisConfigsKey is the hash the configs of the queues kept in redis are saved in
+	redisConfigsKey = "queue-configs"
+)
+
+// backend is where the registry keeps queues along with their configs
+type backend interface {
+	open(name string, opts ...queue.Option) (taskQueue, error)
+	// drop removes the tasks and config of a queue that was closed
+	drop(name string) error
+	saveConfig(name string, c *pb.QueueConfig) error
+	// configs returns the saved configs by queue name
+	configs() (map[string]*pb.QueueConfig, error)
+}
+
+// memoryBackend keeps queues in memory only, they are lost when the server stops
+type memoryBackend struct{}
+
+func (memoryBackend) open(name string, opts ...queue.Option) (taskQueue, error) {
+	return queue.NewQueue[string, *pb.Payload](opts...), nil
+}
+
+func (memoryBackend) drop(name string) error {
+	return nil
+}
+
+func (memoryBackend) saveConfig(name string, c *pb.QueueConfig) error {
+	return nil
+}
+
+func (memoryBackend) configs() (map[string]*pb.QueueConfig, error) {
+	return nil, nil
+}
+
+// diskBackend persists every queue in a directory of its own under dir
+type diskBackend struct {
+	dir        string
+	durability queue.Durability
+}
+
+func (b diskBackend) open(name string, opts ...queue.Option) (taskQueue, error) {
+	return queue.OpenDurable[string](filepath.Join(b.dir, name), b.durability, payloadCodec{}, opts...)
+}
+
+func (b diskBackend) drop(name string) error {
+	return os.RemoveAll(filepath.Join(b.dir, name))
+}
+
+func (b diskBackend) saveConfig(name string, c *pb.QueueConfig) error {
+	data, err := protojson.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(b.dir, name, configFile), data, 0o644)
+}
+
+func (b diskBackend) configs() (map[string]*pb.QueueConfig, error) {
+	paths, err := filepath.Glob(filepath.Join(b.dir, "*", configFile))
+	if err != nil {
+		return nil, err
+	}
+	configs := map[string]*pb.QueueConfig{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		c := &pb.QueueConfig{}
+		if err := protojson.Unmarshal(data, c); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		configs[filepath.Base(filepath.Dir(path))] = c
+	}
+	return configs, nil
+}
+
+// raftBackend keeps queues in memory, replicated to the other servers of a raft cluster by node.
+// Configs are kept in the raft log along with every change made to the queues.
+type raftBackend struct {
+	node *raftNode
+}
+
+func (b raftBackend) open(name string, opts ...queue.Option) (taskQueue, error) {
+	return queue.NewReplicated[string](b.node, name, payloadCodec{}, opts...), nil
+}
+
+func (raftBackend) drop(name string) error {
+	return nil
+}
+
+func (raftBackend) saveConfig(name string, c *pb.QueueConfig) error {
+	return nil
+}
+
+func (raftBackend) configs() (map[string]*pb.QueueConfig, error) {
+	return nil, nil
+}
+
+// redisBackend keeps queues in redis, servers using the same redis share their queues while each
+// enforces the rate limits of a queue on its own. Queues created or deleted at runtime are only
+// picked up by the other servers once they restart.
+type redisBackend struct {
+	rdb redis.UniversalClient
+}
+
+func (b redisBackend) open(name string, opts ...queue.Option) (taskQueue, error) {
+	return queue.OpenRedis[string](b.rdb, name, payloadCodec{}, opts...)
+}
+
+func (b redisBackend) drop(name string) error {
+	if err := b.rdb.HDel(context.Background(), redisConfigsKey, name).Err(); err != nil {
+		return err
+	}
+	return queue.DropRedis(b.rdb, name)
+}
+
+func (b redisBackend) saveConfig(name string, c *pb.QueueConfig) error {
+	data, err := protojson.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return b.rdb.HSet(context.Background(), redisConfigsKey, name, data).Err()
+}
+
+func (b redisBackend) configs() (map[string]*pb.QueueConfig, error) {
+	saved, err := b.rdb.HGetAll(context.Background(), redisConfigsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	configs := map[string]*pb.QueueConfig{}
+	for name, data := range saved {
+		c := &pb.QueueConfig{}
+		if err := protojson.Unmarshal([]byte(data), c); err != nil {
+			return nil, fmt.Errorf("invalid config of queue %s: %w", name, err)
+		}
+		configs[name] = c
+	}
+	return configs, nil
+}
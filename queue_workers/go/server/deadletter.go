@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *server) ListDeadLetters(ctx context.Context, r *pb.DeadLetterListRequest) (*pb.DeadLetterListReply, error) {
+	_, dlqName, dlq, err := s.deadLetterQueue(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	tasks := []*pb.Task{}
+	for _, v := range dlq.List() {
+		tasks = append(tasks, toTask(dlqName, v))
+	}
+	return &pb.DeadLetterListReply{Tasks: tasks}, nil
+}
+
+func (s *server) GetDeadLetter(ctx context.Context, r *pb.DeadLetterGetRequest) (*pb.DeadLetterGetReply, error) {
+	_, dlqName, dlq, err := s.deadLetterQueue(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	v := dlq.Get(r.GetName())
+	if v == nil {
+		return nil, status.Errorf(codes.NotFound, fmt.Sprintf("task %s is not dead lettered in queue %s", r.GetName(), r.GetQueue()))
+	}
+	return &pb.DeadLetterGetReply{Task: toTask(dlqName, v)}, nil
+}
+
+func (s *server) ReplayDeadLetters(ctx context.Context, r *pb.DeadLetterReplayRequest) (*pb.DeadLetterReplayReply, error) {
+	q, _, dlq, err := s.deadLetterQueue(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	names, err := queue.Replay(dlq, q, r.GetNames()...)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.DeadLetterReplayReply{Names: names}, nil
+}
+
+// deadLetterQueue looks up the queue of a consumer group, or qid itself, and the dead letter queue
+// registered next to it along with its name
+func (s *server) deadLetterQueue(qid, group string) (taskQueue, string, taskQueue, error) {
+	_, e, err := s.consumer(qid, group)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	dlq, ok := s.queues.get(e.dlq)
+	if !ok {
+		return nil, "", nil, status.Errorf(codes.FailedPrecondition, fmt.Sprintf("queue %s has no dead letter queue", qid))
+	}
+	return e.q, e.dlq, dlq, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"queue-workers/queue"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// toStatus maps errors returned by queues to grpc status errors
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		// already a status, such as the errors of replicating a change with raft
+		return err
+	}
+	code := codes.Internal
+	switch {
+	case errors.Is(err, queue.ErrDuplicateKey), errors.Is(err, queue.ErrAlreadyDone):
+		code = codes.AlreadyExists
+	case errors.Is(err, queue.ErrNotFound), errors.Is(err, queue.ErrLeaseNotFound):
+		code = codes.NotFound
+	case errors.Is(err, queue.ErrQueueFull):
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, err.Error())
+}
+
+// exhausted is a RESOURCE_EXHAUSTED error telling the client to retry after d
+func exhausted(d time.Duration, format string, args ...any) error {
+	st := status.New(codes.ResourceExhausted, fmt.Sprintf(format, args...))
+	if ds, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(d)}); err == nil {
+		st = ds
+	}
+	return st.Err()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// eventLogSize is how many events of a queue are kept for watches resuming where they dropped
+const eventLogSize = 1024
+
+// errLogClosed is returned by eventLog.read once the queue is closed and every event was read
+var errLogClosed = errors.New("event log closed")
+
+// watchEvent is a queue event numbered in the order it happened
+type watchEvent struct {
+	seq uint64
+	queue.Event[string, *pb.Payload]
+}
+
+// eventLog numbers the events of a queue streamed to watchers and keeps the last ones around so
+// a watch that dropped can be resumed without missing any. It holds the only subscription to
+// the queue, watchers read from the log instead. Numbers start at 1 when the queue is opened, under
+// a new id so watches numbered by another log, from before a restart or on another server, are
+// not resumed at the wrong place.
+type eventLog struct {
+	mux  sync.Mutex
+	id   string
+	size int
+	// the last size events at most, oldest first
+	events []watchEvent
+	// sequence number of the next event
+	next uint64
+	// closed and replaced whenever an event is logged, closed for good once the queue is
+	changed  chan struct{}
+	closed   bool
+	watchers int
+}
+
+func newEventLog(q taskQueue, size int) *eventLog {
+	l := &eventLog{id: newLogID(), size: size, next: 1, changed: make(chan struct{})}
+	go l.run(q.Subscribe())
+	return l
+}
+
+func newLogID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// run logs the events of sub until the queue is closed
+func (l *eventLog) run(sub *queue.Subscription[string, *pb.Payload]) {
+	defer sub.Close()
+	for e := range sub.C() {
+		if _, ok := watchEvents[e.Type]; !ok {
+			continue
+		}
+		l.mux.Lock()
+		if len(l.events) == l.size {
+			l.events = l.events[1:]
+		}
+		l.events = append(l.events, watchEvent{seq: l.next, Event: e})
+		l.next++
+		close(l.changed)
+		l.changed = make(chan struct{})
+		l.mux.Unlock()
+	}
+	l.mux.Lock()
+	l.closed = true
+	close(l.changed)
+	l.mux.Unlock()
+}
+
+// start returns the sequence number of the next event, where watches streaming from now on start
+func (l *eventLog) start() uint64 {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.next
+}
+
+// resume checks that a watch numbered by the log with the given id can resume from this one
+func (l *eventLog) resume(id string) error {
+	if id != l.id {
+		return status.Errorf(codes.FailedPrecondition, fmt.Sprintf("events are numbered by log %s, not by log %q the watch resumes from", l.id, id))
+	}
+	return nil
+}
+
+// read returns the events logged from sequence number from on, along with a channel closed once
+// more are logged. It fails when the events asked for are no longer kept or were never logged.
+func (l *eventLog) read(from uint64) ([]watchEvent, <-chan struct{}, error) {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	first := l.next - uint64(len(l.events))
+	switch {
+	case from < first:
+		return nil, nil, status.Errorf(codes.OutOfRange, fmt.Sprintf("events before %d are no longer kept", first))
+	case from > l.next:
+		return nil, nil, status.Errorf(codes.OutOfRange, fmt.Sprintf("event %d was never sent, the last one is %d", from, l.next-1))
+	case from == l.next && l.closed:
+		return nil, nil, errLogClosed
+	}
+	return append([]watchEvent(nil), l.events[from-first:]...), l.changed, nil
+}
+
+// watch counts an open watch until the returned func is called
+func (l *eventLog) watch() func() {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.watchers++
+	return func() {
+		l.mux.Lock()
+		defer l.mux.Unlock()
+		l.watchers--
+	}
+}
+
+// numWatchers is the number of open watches on the queue
+func (l *eventLog) numWatchers() int {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	return l.watchers
+}
@@ -2,80 +2,684 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
 	pb "queue-workers/proto"
 	"queue-workers/queue"
-	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"github.com/hashicorp/raft"
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultLeaseDuration = 30 * time.Second
+
+var (
+	queues = flag.String("queues", "q1=fifo:5,q2=fifo:5", "comma separated queues to create on startup as name=order[:max_attempts], "+
+		"order is one of [fifo, lifo, priority], tasks failing max_attempts leases move to the name.dlq queue, 0 disables dead lettering")
+	dataDir   = flag.String("data-dir", "", "directory to persist queues in, queues are kept in memory only when empty")
+	redisAddr = flag.String("redis-addr", "", "address of a redis server to keep queues in instead of memory or -data-dir, "+
+		"servers using the same redis share their queues")
+	fsync           = flag.String("fsync", "always", "when to fsync the write ahead log of persisted queues, one of [always, batch, none]")
+	syncInterval    = flag.Duration("fsync-interval", 100*time.Millisecond, "how often the write ahead log is fsynced with -fsync batch")
+	snapInterval    = flag.Duration("snapshot-interval", time.Minute, "how often persisted queues are compacted into a snapshot")
+	shutdownTimeout = flag.Duration("shutdown-timeout", 30*time.Second, "how long rpcs in progress are given to finish on SIGTERM or SIGINT before being cancelled")
+	metricsAddr     = flag.String("metrics-addr", ":9090", "address to serve prometheus metrics at under /metrics, empty disables them")
+	listenAddr      = flag.String("addr", ":8000", "address to serve grpc at")
+	raftID          = flag.String("raft-id", "", "id of this server in a raft cluster replicating the queues to -raft-peers, queues are not replicated when empty")
+	raftPeers       = flag.String("raft-peers", "", "comma separated servers of the raft cluster as id=raft_addr/grpc_addr, this one included. "+
+		"Every server must be given the same peers and -queues.")
+	raftDir = flag.String("raft-dir", "", "directory to keep the raft log and snapshots in, they are kept in memory only when empty")
+	aclFile = flag.String("acl", "", "json file of the bearer tokens and rules clients are authenticated and authorized with, "+
+		"every client is allowed everything when empty")
+	tlsCert     = flag.String("tls-cert", "", "certificate to serve grpc over tls with, grpc is served in plain text when empty")
+	tlsKey      = flag.String("tls-key", "", "private key of -tls-cert")
+	tlsClientCA = flag.String("tls-client-ca", "", "ca to verify client certificates with, their common name authenticates them to -acl")
 )
 
 type server struct {
-	q map[string]queue.Queue
+	queues *registry
+	// set when the queues are replicated with raft, nil otherwise
+	cluster *raftNode
+	// authenticates and authorizes clients, every client is allowed everything when nil
+	acl *acl
+	// closed by drain, adds are refused and watches end once it is
+	draining  chan struct{}
+	drainOnce sync.Once
 	pb.UnimplementedQueueServer
 }
 
+// entry looks up the queue qid named by a client, dead letter and consumer group queues are
+// only reached through the queue they belong to
+func (s *server) entry(qid string) (*entry, error) {
+	if reservedName(qid) {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("queue name %s is reserved for dead letter and consumer group queues", qid))
+	}
+	e, ok := s.queues.lookup(qid)
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("requested queue %s is not present", qid))
+	}
+	return e, nil
+}
+
+// consumer looks up the queue tasks of qid are taken from by the consumer group group, that of the
+// group on queues with consumer groups and qid itself otherwise. It returns the name the queue is
+// registered under.
+func (s *server) consumer(qid, group string) (string, *entry, error) {
+	e, err := s.entry(qid)
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case len(e.groups) == 0 && group != "":
+		return "", nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("queue %s has no consumer groups", qid))
+	case len(e.groups) == 0:
+		return qid, e, nil
+	case group == "":
+		return "", nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("queue %s has consumer groups, a group is required", qid))
+	}
+	name := groupQueueName(qid, group)
+	ge, ok := s.queues.lookup(name)
+	if !ok {
+		return "", nil, status.Errorf(codes.NotFound, fmt.Sprintf("queue %s has no consumer group %s", qid, group))
+	}
+	return name, ge, nil
+}
+
 func (s *server) AddTask(ctx context.Context, r *pb.TaskAddRequest) (*pb.TaskAddReply, error) {
-	qid := r.GetTask().GetQueue()
-	q, ok := s.q[qid]
+	reply, err := s.addTask(ctx, r.GetTask(), r.GetUpsert())
+	if err != nil {
+		return nil, err
+	}
+	reply.Msg = fmt.Sprintf("task %s added to queue %s", r.GetTask().Name, r.GetTask().Queue)
+	return reply, nil
+}
+
+func (s *server) AddTasks(ctx context.Context, r *pb.TasksAddRequest) (*pb.TasksAddReply, error) {
+	results := []*pb.TaskAddResult{}
+	for _, t := range r.GetTasks() {
+		res := &pb.TaskAddResult{Name: t.GetName(), Queue: t.GetQueue(), Msg: "task added"}
+		reply, err := s.addTask(ctx, t, r.GetUpsert())
+		if err != nil {
+			st := status.Convert(err)
+			res.Code, res.Msg = int32(st.Code()), st.Message()
+		} else {
+			res.Duplicate, res.Original = reply.GetDuplicate(), reply.GetOriginal()
+		}
+		results = append(results, res)
+	}
+	return &pb.TasksAddReply{Results: results}, nil
+}
+
+// addTask reports whether t was a duplicate on a queue with a dedup window, in which case nothing
+// is added and the reply carries the status of the task added before, from its first consumer
+// group when the queue has some
+func (s *server) addTask(ctx context.Context, t *pb.Task, upsert bool) (*pb.TaskAddReply, error) {
+	if s.isDraining() {
+		return nil, errDraining
+	}
+	e, err := s.entry(t.GetQueue())
+	if err != nil {
+		return nil, err
+	}
+	opts, err := addOptions(t)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.limits.allowEnqueue(ctx, t.GetQueue()); err != nil {
+		return nil, err
+	}
+	if upsert {
+		opts = append(opts, queue.WithUpsert())
+	}
+	// adds to BLOCK queues wait for room until the call is cancelled
+	opts = append(opts, queue.WithContext(ctx))
+	if len(e.groups) == 0 {
+		return s.add(e, t, opts)
+	}
+	// every group gets the task, the groups it was added to are rolled back when one of them
+	// refuses it so a failed add can be retried as a whole. Upserts roll back by putting the
+	// content they replaced back, the only thing an upsert changes.
+	undo := []func(){}
+	var first *pb.TaskAddReply
+	dups := 0
+	for _, g := range e.groups {
+		_, ge, err := s.consumer(t.GetQueue(), g)
+		if err != nil {
+			return nil, err
+		}
+		var prev *queue.Item[string, *pb.Payload]
+		if upsert {
+			prev = ge.q.Get(t.GetName())
+		}
+		reply, err := s.add(ge, t, opts)
+		if err != nil {
+			for _, f := range undo {
+				f()
+			}
+			return nil, err
+		}
+		if first == nil {
+			first = reply
+		}
+		switch {
+		case reply.GetDuplicate():
+			dups++
+		case prev != nil:
+			_, v := prev.KeyValue()
+			undo = append(undo, func() { ge.q.Add(t.GetName(), v, queue.WithUpsert()) })
+		default:
+			undo = append(undo, func() { ge.q.Remove(t.GetName()) })
+		}
+	}
+	if dups < len(e.groups) {
+		return &pb.TaskAddReply{}, nil
+	}
+	return first, nil
+}
+
+// add adds t to the queue of e, reporting duplicates as addTask does
+func (s *server) add(e *entry, t *pb.Task, opts []queue.AddOption) (*pb.TaskAddReply, error) {
+	err := e.q.Add(t.GetName(), payloadOf(t), opts...)
+	if e.config.GetDedupWindow() != nil && (errors.Is(err, queue.ErrDuplicateKey) || errors.Is(err, queue.ErrAlreadyDone)) {
+		// retried adds get the reply of the add that went through
+		reply := &pb.TaskAddReply{Duplicate: true}
+		if st, ok := e.q.Status(t.GetName()); ok {
+			reply.Original = taskReply(st)
+		}
+		return reply, nil
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.TaskAddReply{}, nil
+}
+
+func (s *server) RemoveTask(ctx context.Context, r *pb.TaskRemoveRequest) (*pb.TaskRemoveReply, error) {
+	e, err := s.entry(r.GetQueue())
+	if err != nil {
+		return nil, err
+	}
+	groups := []string{r.GetGroup()}
+	if len(e.groups) > 0 && r.GetGroup() == "" {
+		groups = e.groups
+	}
+	var removed *queue.Item[string, *pb.Payload]
+	for _, g := range groups {
+		_, ge, err := s.consumer(r.GetQueue(), g)
+		if err != nil {
+			return nil, err
+		}
+		v, err := ge.q.Remove(r.GetName())
+		if err != nil && (len(groups) == 1 || !errors.Is(err, queue.ErrNotFound)) {
+			return nil, toStatus(err)
+		}
+		if removed == nil {
+			removed = v
+		}
+	}
+	if removed == nil {
+		return nil, toStatus(fmt.Errorf("task %s: %w", r.GetName(), queue.ErrNotFound))
+	}
+	return &pb.TaskRemoveReply{Task: toTask(r.GetQueue(), removed)}, nil
+}
+
+func (s *server) PopTasks(ctx context.Context, r *pb.TasksPopRequest) (*pb.TasksPopReply, error) {
+	_, e, err := s.consumer(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	if r.GetMaxN() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("max_n must be positive, got %d", r.GetMaxN()))
+	}
+	items, err := e.q.PopN(int(r.GetMaxN()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	tasks := []*pb.Task{}
+	for _, v := range items {
+		tasks = append(tasks, toTask(r.GetQueue(), v))
+	}
+	return &pb.TasksPopReply{Tasks: tasks}, nil
+}
+
+func (s *server) PeekTasks(ctx context.Context, r *pb.TasksPeekRequest) (*pb.TasksPeekReply, error) {
+	_, e, err := s.consumer(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	if r.GetMaxN() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("max_n must be positive, got %d", r.GetMaxN()))
+	}
+	tasks := []*pb.Task{}
+	for _, v := range e.q.List() {
+		if len(tasks) == int(r.GetMaxN()) {
+			break
+		}
+		tasks = append(tasks, toTask(r.GetQueue(), v))
+	}
+	return &pb.TasksPeekReply{Tasks: tasks}, nil
+}
+
+// addOptions reads the per task settings of t
+func addOptions(t *pb.Task) ([]queue.AddOption, error) {
+	opts := []queue.AddOption{queue.WithPriority(int(t.GetPriority()))}
+	switch {
+	case t.GetNotBefore() != nil && t.GetDelay() != nil:
+		return nil, status.Errorf(codes.InvalidArgument, "only one of not_before and delay can be set")
+	case t.GetNotBefore() != nil:
+		opts = append(opts, queue.WithNotBefore(t.GetNotBefore().AsTime()))
+	case t.GetDelay() != nil:
+		opts = append(opts, queue.WithDelay(t.GetDelay().AsDuration()))
+	}
+	switch {
+	case t.GetExpiresAt() != nil && t.GetTtl() != nil:
+		return nil, status.Errorf(codes.InvalidArgument, "only one of expires_at and ttl can be set")
+	case t.GetExpiresAt() != nil:
+		opts = append(opts, queue.WithExpiresAt(t.GetExpiresAt().AsTime()))
+	case t.GetTtl() != nil:
+		if t.GetTtl().AsDuration() <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("ttl must be positive, got %s", t.GetTtl().AsDuration()))
+		}
+		opts = append(opts, queue.WithExpiresIn(t.GetTtl().AsDuration()))
+	}
+	return opts, nil
+}
+
+func (s *server) LeaseTask(ctx context.Context, r *pb.TaskLeaseRequest) (*pb.TaskLeaseReply, error) {
+	qid, e, err := s.consumer(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	d := defaultLeaseDuration
+	if r.GetLeaseDuration() != nil {
+		d = r.GetLeaseDuration().AsDuration()
+	}
+	if d <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("lease duration must be positive, got %s", d))
+	}
+	l, err := e.limits.lease(e.q, qid, d)
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return &pb.TaskLeaseReply{}, nil
+	}
+	return &pb.TaskLeaseReply{
+		Task:      toTask(r.GetQueue(), l.Item()),
+		LeaseId:   joinLeaseID(qid, l.ID()),
+		ExpiresAt: timestamppb.New(l.ExpiresAt()),
+	}, nil
+}
+
+func (s *server) AckTask(ctx context.Context, r *pb.TaskAckRequest) (*pb.TaskAckReply, error) {
+	q, id, err := s.leaseQueue(r.GetLeaseId())
+	if err != nil {
+		return nil, err
+	}
+	if err := q.Ack(id, queue.WithResult(r.GetResult())); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.TaskAckReply{Msg: fmt.Sprintf("lease %s acked", r.GetLeaseId())}, nil
+}
+
+func (s *server) NackTask(ctx context.Context, r *pb.TaskNackRequest) (*pb.TaskNackReply, error) {
+	q, id, err := s.leaseQueue(r.GetLeaseId())
+	if err != nil {
+		return nil, err
+	}
+	opts := []queue.SettleOption{}
+	if r.GetError() != "" {
+		opts = append(opts, queue.WithError(r.GetError()))
+	}
+	if err := q.Nack(id, opts...); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.TaskNackReply{Msg: fmt.Sprintf("lease %s nacked", r.GetLeaseId())}, nil
+}
+
+func (s *server) GetTask(ctx context.Context, r *pb.TaskGetRequest) (*pb.TaskGetReply, error) {
+	_, e, err := s.consumer(r.GetQueue(), r.GetGroup())
+	if err != nil {
+		return nil, err
+	}
+	st, ok := e.q.Status(r.GetName())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, fmt.Sprintf("task %s is not in queue %s nor settled recently", r.GetName(), r.GetQueue()))
+	}
+	return taskReply(st), nil
+}
+
+// taskReply is what GetTask reports for a task with status st
+func taskReply(st queue.Status) *pb.TaskGetReply {
+	reply := &pb.TaskGetReply{
+		State:     taskStates[st.State],
+		Attempts:  int32(st.Attempts),
+		LastError: st.LastError,
+		Result:    st.Result,
+	}
+	if !st.DoneAt.IsZero() {
+		reply.DoneAt = timestamppb.New(st.DoneAt)
+	}
+	return reply
+}
+
+var taskStates = map[queue.State]pb.TaskState{
+	queue.Pending:   pb.TaskState_PENDING,
+	queue.Leased:    pb.TaskState_LEASED,
+	queue.Succeeded: pb.TaskState_SUCCEEDED,
+	queue.Failed:    pb.TaskState_FAILED,
+	queue.Dead:      pb.TaskState_DEAD,
+}
+
+// lease ids handed to clients are prefixed with the queue name so acks can be routed without extra state
+func joinLeaseID(qid, id string) string {
+	return qid + "/" + id
+}
+
+// splitLeaseID is the inverse of joinLeaseID, the acl checks the queue it returns so it is the
+// only place lease ids handed to clients are taken apart
+func splitLeaseID(leaseID string) (qid, id string, ok bool) {
+	i := strings.LastIndex(leaseID, "/")
+	if i < 0 {
+		return "", "", false
+	}
+	return leaseID[:i], leaseID[i+1:], true
+}
+
+func (s *server) leaseQueue(leaseID string) (taskQueue, string, error) {
+	qid, id, ok := splitLeaseID(leaseID)
 	if !ok {
-		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("requested queue %s is not present", r.GetTask().Queue))
+		return nil, "", status.Errorf(codes.InvalidArgument, fmt.Sprintf("malformed lease id %s", leaseID))
 	}
-	q.Add(queue.Key(r.GetTask().GetName()), r.GetTask().GetPayload())
-	return &pb.TaskAddReply{Msg: fmt.Sprintf("task %s added to queue %s", r.GetTask().Name, r.GetTask().Queue)}, nil
+	q, ok := s.queues.get(qid)
+	if !ok {
+		return nil, "", status.Errorf(codes.NotFound, fmt.Sprintf("queue of lease %s is not present", leaseID))
+	}
+	return q, id, nil
+}
+
+func toTask(qid string, i *queue.Item[string, *pb.Payload]) *pb.Task {
+	k, v := i.KeyValue()
+	t := &pb.Task{Name: k, Payload: v, Queue: qid, Priority: int32(i.Priority()), Attempts: int32(i.Attempts())}
+	if text, ok := v.GetKind().(*pb.Payload_Text); ok {
+		//lint:ignore SA1019 kept up to date for older clients
+		t.Text = text.Text
+	}
+	if !i.NotBefore().IsZero() {
+		t.NotBefore = timestamppb.New(i.NotBefore())
+	}
+	if !i.ExpiresAt().IsZero() {
+		t.ExpiresAt = timestamppb.New(i.ExpiresAt())
+	}
+	return t
 }
 
 func (s *server) WatchQueue(r *pb.TaskWatchRequest, w pb.Queue_WatchQueueServer) error {
 	qid := r.Queue
-	q, ok := s.q[qid]
-	if !ok {
-		return status.Errorf(codes.InvalidArgument, fmt.Sprintf("requested queue %s is not present", r.Queue))
+	_, e, err := s.consumer(qid, r.GetGroup())
+	if err != nil {
+		return err
 	}
-	timer := time.Tick(1 * time.Second)
-	lastSeen := q.Peep()
-	errChan := make(chan error)
-	go func() {
-		for {
-			select {
-			case <-timer:
-				seen := q.Peep()
-				if reflect.DeepEqual(lastSeen, seen) {
-					log.Printf("no updates to %+v, waiting ...", seen)
-					continue
-				}
-				k, v := seen.KeyValue()
-				pl := v.(string)
-				log.Printf("sending event for new item in the queue: %+v", seen)
-				if err := w.Send(&pb.TaskWatchReply{Task: &pb.Task{Name: string(k), Payload: pl, Queue: qid}}); err != nil {
-					errChan <- status.Errorf(codes.Internal, fmt.Sprintf("failed to stream event: %s", err))
-				}
-				lastSeen = seen
-			case <-w.Context().Done():
-				errChan <- status.Errorf(codes.DeadlineExceeded, "client side timeout exceeded")
+	if s.isDraining() {
+		return errDraining
+	}
+	from := r.GetFromSequence()
+	if from == 0 {
+		from = e.events.start()
+	} else if err := e.events.resume(r.GetLogId()); err != nil {
+		return err
+	}
+	defer e.events.watch()()
+	// flush headers so clients can tell the watch is in place before the first event
+	if err := w.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		events, changed, err := e.events.read(from)
+		if errors.Is(err, errLogClosed) {
+			return status.Errorf(codes.Unavailable, fmt.Sprintf("queue %s was closed", qid))
+		}
+		if err != nil {
+			return err
+		}
+		for _, evt := range events {
+			reply := &pb.TaskWatchReply{Task: toTask(qid, evt.Item), Event: watchEvents[evt.Type], Sequence: evt.seq, LogId: e.events.id}
+			if err := w.Send(reply); err != nil {
+				return status.Errorf(codes.Internal, fmt.Sprintf("failed to stream event: %s", err))
 			}
+			from = evt.seq + 1
 		}
-	}()
-	return <-errChan
+		select {
+		case <-changed:
+		case <-s.draining:
+			return errDraining
+		case <-w.Context().Done():
+			log.Printf("watch on queue %s ended: %s", qid, w.Context().Err())
+			return status.FromContextError(w.Context().Err()).Err()
+		}
+	}
+}
+
+// watchEvents are the queue events streamed to watchers, the ones making a task available and expiries
+var watchEvents = map[queue.EventType]pb.TaskEvent{
+	queue.EventAdded:    pb.TaskEvent_ADDED,
+	queue.EventRequeued: pb.TaskEvent_REQUEUED,
+	queue.EventExpired:  pb.TaskEvent_EXPIRED,
+}
+
+// parseQueues reads queue configs from a spec like "q1=fifo:5,q2=priority"
+func parseQueues(spec string) ([]string, map[string]*pb.QueueConfig, error) {
+	names := []string{}
+	configs := map[string]*pb.QueueConfig{}
+	for _, entry := range strings.Split(spec, ",") {
+		name, conf, _ := strings.Cut(strings.TrimSpace(entry), "=")
+		if name == "" {
+			return nil, nil, fmt.Errorf("missing queue name in %q", entry)
+		}
+		if _, ok := configs[name]; ok {
+			return nil, nil, fmt.Errorf("queue %s is configured more than once", name)
+		}
+		order, attempts, _ := strings.Cut(conf, ":")
+		c := &pb.QueueConfig{}
+		if order != "" {
+			o, ok := pb.QueueOrder_value[strings.ToUpper(order)]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown order %q for queue %s", order, name)
+			}
+			c.Order = pb.QueueOrder(o)
+		}
+		if attempts != "" {
+			n, err := strconv.Atoi(attempts)
+			if err != nil || n < 0 {
+				return nil, nil, fmt.Errorf("invalid max attempts %q for queue %s", attempts, name)
+			}
+			c.MaxAttempts = int32(n)
+		}
+		names = append(names, name)
+		configs[name] = c
+	}
+	return names, configs, nil
+}
+
+// newServer serves the queues saved in b, creating the ones in spec that do not exist yet
+func newServer(spec string, b backend) (*server, error) {
+	names, configs, err := parseQueues(spec)
+	if err != nil {
+		return nil, err
+	}
+	reg := newRegistry(b)
+	if err := reg.restore(); err != nil {
+		return nil, fmt.Errorf("failed to restore queues: %w", err)
+	}
+	for _, name := range names {
+		if _, ok := reg.get(name); ok {
+			continue
+		}
+		if _, err := reg.create(name, configs[name]); err != nil {
+			return nil, err
+		}
+	}
+	srv := &server{queues: reg, draining: make(chan struct{})}
+	if rb, ok := b.(raftBackend); ok {
+		// the queues of spec are the same on every server, only those created later go through the log
+		if err := rb.node.start(reg); err != nil {
+			return nil, err
+		}
+		srv.cluster = rb.node
+	}
+	return srv, nil
+}
+
+// newGRPCServer registers srv on a grpc server that records the latency of its rpcs in m,
+// rpcs from clients the acl of srv refuses are failed and rpcs making changes are forwarded
+// to the leader when srv is part of a raft cluster. The acl interceptors come before anything
+// rate limiting the rpcs so clients are limited by the identity they authenticated with.
+func newGRPCServer(srv *server, m *metrics, opts ...grpc.ServerOption) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{m.unaryInterceptor}
+	stream := []grpc.StreamServerInterceptor{m.streamInterceptor}
+	if srv.acl != nil {
+		unary = append(unary, srv.acl.unaryInterceptor)
+		stream = append(stream, srv.acl.streamInterceptor)
+	}
+	if srv.cluster != nil {
+		unary = append(unary, srv.cluster.unaryInterceptor)
+	}
+	opts = append(opts, grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+	grpcServ := grpc.NewServer(opts...)
+	pb.RegisterQueueServer(grpcServ, srv)
+	return grpcServ
+}
+
+// openBackend picks where queues are kept from the flags
+func openBackend() (backend, error) {
+	switch {
+	case *redisAddr != "" && *dataDir != "":
+		return nil, errors.New("-redis-addr and -data-dir are mutually exclusive")
+	case *raftID != "" && (*redisAddr != "" || *dataDir != ""):
+		return nil, errors.New("-raft-id is mutually exclusive with -redis-addr and -data-dir")
+	case *raftID != "":
+		peers, err := parseRaftPeers(*raftPeers)
+		if err != nil {
+			return nil, err
+		}
+		self := ""
+		for _, p := range peers {
+			if p.id == *raftID {
+				self = p.raftAddr
+			}
+		}
+		if self == "" {
+			return nil, fmt.Errorf("raft id %s is not one of -raft-peers", *raftID)
+		}
+		trans, err := raft.NewTCPTransport(self, nil, 3, 10*time.Second, os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen for raft at %s: %w", self, err)
+		}
+		log.Printf("replicating queues with raft as %s", *raftID)
+		return raftBackend{node: newRaftNode(*raftID, peers, *raftDir, trans)}, nil
+	case *redisAddr != "":
+		rdb := redis.NewClient(&redis.Options{Addr: *redisAddr})
+		if err := rdb.Ping(context.Background()).Err(); err != nil {
+			return nil, fmt.Errorf("failed to reach redis at %s: %w", *redisAddr, err)
+		}
+		log.Printf("keeping queues in redis at %s", *redisAddr)
+		return redisBackend{rdb: rdb}, nil
+	case *dataDir != "":
+		policy, err := queue.ParseSyncPolicy(*fsync)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fsync policy: %w", err)
+		}
+		log.Printf("persisting queues to %s, fsync %s", *dataDir, policy)
+		return diskBackend{dir: *dataDir, durability: queue.Durability{Sync: policy, SyncInterval: *syncInterval, SnapshotInterval: *snapInterval}}, nil
+	default:
+		return memoryBackend{}, nil
+	}
+}
+
+// securityOptions sets up the acl of srv and the tls the grpc server is served over from the flags
+func securityOptions(srv *server) ([]grpc.ServerOption, error) {
+	if *aclFile != "" {
+		a, err := loadACL(*aclFile)
+		if err != nil {
+			return nil, err
+		}
+		srv.acl = a
+		log.Printf("authorizing clients with the acl %s", *aclFile)
+	}
+	if *tlsCert == "" {
+		if *tlsClientCA != "" {
+			return nil, errors.New("-tls-client-ca requires -tls-cert")
+		}
+		return nil, nil
+	}
+	if *raftID != "" {
+		// rpcs are forwarded to the leader in plain text
+		return nil, errors.New("-tls-cert is not supported along with -raft-id")
+	}
+	c, err := serverTLS(*tlsCert, *tlsKey, *tlsClientCA)
+	if err != nil {
+		return nil, err
+	}
+	return []grpc.ServerOption{grpc.Creds(credentials.NewTLS(c))}, nil
 }
 
 func main() {
-	tcpListener, err := net.Listen("tcp", ":8000")
+	flag.Parse()
+	b, err := openBackend()
+	if err != nil {
+		log.Fatalf("invalid storage config: %s", err)
+	}
+	srv, err := newServer(*queues, b)
+	if err != nil {
+		log.Fatalf("invalid queue config: %s", err)
+	}
+	opts, err := securityOptions(srv)
+	if err != nil {
+		log.Fatalf("invalid security config: %s", err)
+	}
+	tcpListener, err := net.Listen("tcp", *listenAddr)
 	if err != nil {
 		log.Fatalf("failed to listen: %s", err)
 	}
-	grpcServ := grpc.NewServer()
-	pb.RegisterQueueServer(grpcServ, &server{
-		q: map[string]queue.Queue{
-			"q1": queue.NewQueue(), "q2": queue.NewQueue(),
-		},
-	})
-	log.Print("server listening at :8000")
+	m := newMetrics(srv.queues)
+	if *metricsAddr != "" {
+		metricsServ := serveMetrics(*metricsAddr, newMetricsRegistry(m))
+		defer metricsServ.Close()
+	}
+	grpcServ := newGRPCServer(srv, m, opts...)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		<-ctx.Done()
+		// a second signal kills the server right away
+		stop()
+		log.Print("shutting down")
+		if err := srv.shutdown(grpcServ, *shutdownTimeout); err != nil {
+			log.Printf("failed to close queues: %s", err)
+		}
+	}()
+	log.Printf("server listening at %s", *listenAddr)
 	if err := grpcServ.Serve(tcpListener); err != nil {
 		log.Fatalf("failed to server grpc: %s", err)
 	}
+	// Serve returns once shutdown stopped the grpc server, the queues may still be flushing
+	<-closed
 }
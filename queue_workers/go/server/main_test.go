@@ -0,0 +1,1366 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hashicorp/raft"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// startServer serves queues built from spec over an in-memory listener
+func startServer(t *testing.T, spec string) pb.QueueClient {
+	t.Helper()
+	cli, _ := startMetricsServer(t, spec)
+	return cli
+}
+
+// startMetricsServer is startServer also returning the registry its metrics are exported from
+func startMetricsServer(t *testing.T, spec string) (pb.QueueClient, *prometheus.Registry) {
+	t.Helper()
+	srv, err := newServer(spec, memoryBackend{})
+	require.NoError(t, err)
+	cli, _, reg := serve(t, srv)
+	return cli, reg
+}
+
+// serve serves srv over an in-memory listener
+func serve(t *testing.T, srv *server) (pb.QueueClient, *grpc.Server, *prometheus.Registry) {
+	t.Helper()
+	m := newMetrics(srv.queues)
+	lis := bufconn.Listen(1 << 20)
+	grpcServ := newGRPCServer(srv, m)
+	go grpcServ.Serve(lis)
+	t.Cleanup(grpcServ.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewQueueClient(conn), grpcServ, newMetricsRegistry(m)
+}
+
+func text(s string) *pb.Payload {
+	return &pb.Payload{Kind: &pb.Payload_Text{Text: s}}
+}
+
+func TestWatchQueue(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	// headers are sent once the watch is registered
+	_, err = w.Header()
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name, Payload: text(name)}})
+		require.NoError(t, err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		r, err := w.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, r.GetTask().GetName(), name)
+		assert.Equal(t, r.GetEvent(), pb.TaskEvent_ADDED)
+	}
+
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	assert.Equal(t, l.GetTask().GetName(), "a")
+	_, err = cli.NackTask(ctx, &pb.TaskNackRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+	r, err := w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, r.GetTask().GetName(), "a")
+	assert.Equal(t, r.GetEvent(), pb.TaskEvent_REQUEUED)
+	assert.Equal(t, r.GetTask().GetAttempts(), int32(1))
+}
+
+func TestWatchResume(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	add := func(name string) {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name}})
+		require.NoError(t, err)
+	}
+
+	wctx, stop := context.WithCancel(ctx)
+	w, err := cli.WatchQueue(wctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+	add("a")
+	r, err := w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, r.GetSequence(), uint64(1))
+	// the watch drops and misses what is added until it is resumed
+	stop()
+	add("b")
+	add("c")
+
+	w, err = cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1", FromSequence: r.GetSequence() + 1, LogId: r.GetLogId()})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+	add("d")
+	for i, name := range []string{"b", "c", "d"} {
+		r, err := w.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, r.GetTask().GetName(), name)
+		assert.Equal(t, r.GetSequence(), uint64(i+2))
+	}
+
+	w, err = cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1", FromSequence: 10, LogId: r.GetLogId()})
+	require.NoError(t, err)
+	_, err = w.Recv()
+	assert.Equal(t, status.Code(err), codes.OutOfRange)
+	// sequences of another log, such as one from before a restart, are not resumed from
+	for _, id := range []string{"", "0123456789abcdef"} {
+		w, err = cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1", FromSequence: 2, LogId: id})
+		require.NoError(t, err)
+		_, err = w.Recv()
+		assert.Equal(t, status.Code(err), codes.FailedPrecondition, id)
+	}
+}
+
+func TestWatchResumeReopened(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	watch := func(r *pb.TaskWatchRequest, name string) (*pb.TaskWatchReply, error) {
+		w, err := cli.WatchQueue(ctx, r)
+		require.NoError(t, err)
+		_, err = w.Header()
+		require.NoError(t, err)
+		_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "jobs", Name: name}})
+		require.NoError(t, err)
+		return w.Recv()
+	}
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "jobs"})
+	require.NoError(t, err)
+	first, err := watch(&pb.TaskWatchRequest{Queue: "jobs"}, "foo")
+	require.NoError(t, err)
+
+	// the queue opened again numbers its events from 1 as well, under another log
+	_, err = cli.DeleteQueue(ctx, &pb.QueueDeleteRequest{Name: "jobs"})
+	require.NoError(t, err)
+	_, err = cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "jobs"})
+	require.NoError(t, err)
+	r, err := watch(&pb.TaskWatchRequest{Queue: "jobs"}, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, r.GetSequence(), first.GetSequence())
+	assert.NotEqual(t, r.GetLogId(), first.GetLogId())
+	_, err = watch(&pb.TaskWatchRequest{Queue: "jobs", FromSequence: first.GetSequence(), LogId: first.GetLogId()}, "bar")
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
+
+func TestEventLogBound(t *testing.T) {
+	q := queue.NewQueue[string, *pb.Payload]()
+	l := newEventLog(q, 2)
+	for _, name := range []string{"a", "b", "c"} {
+		require.NoError(t, q.Add(name, text(name)))
+	}
+	assert.Eventually(t, func() bool { return l.start() == 4 }, time.Second, 5*time.Millisecond)
+	_, _, err := l.read(1)
+	assert.Equal(t, status.Code(err), codes.OutOfRange)
+	events, _, err := l.read(2)
+	require.NoError(t, err)
+	names := []string{}
+	for _, e := range events {
+		k, _ := e.Item.KeyValue()
+		names = append(names, k)
+	}
+	assert.Equal(t, names, []string{"b", "c"})
+
+	// events are read up to the last one once the queue is closed
+	require.NoError(t, q.Close())
+	assert.Eventually(t, func() bool {
+		_, _, err := l.read(4)
+		return errors.Is(err, errLogClosed)
+	}, time.Second, 5*time.Millisecond)
+	events, _, err = l.read(3)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+}
+
+func TestQueueAdmin(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "bulk", Config: &pb.QueueConfig{Order: pb.QueueOrder_PRIORITY, MaxSize: 2, MaxAttempts: 3}})
+	require.NoError(t, err)
+	_, err = cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "bulk"})
+	assert.Equal(t, status.Code(err), codes.AlreadyExists)
+	_, err = cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "sneaky.dlq"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+
+	list, err := cli.ListQueues(ctx, &pb.QueueListRequest{})
+	require.NoError(t, err)
+	names := []string{}
+	for _, q := range list.GetQueues() {
+		names = append(names, q.GetName())
+	}
+	assert.Equal(t, names, []string{"bulk", "bulk.dlq", "q1"})
+	assert.Equal(t, list.GetQueues()[0].GetDeadLetterQueue(), "bulk.dlq")
+
+	for _, name := range []string{"a", "b"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "bulk", Name: name}})
+		require.NoError(t, err)
+	}
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "bulk", Name: "c"}})
+	assert.Equal(t, status.Code(err), codes.ResourceExhausted)
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "bulk"})
+	require.NoError(t, err)
+
+	st, err := cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "bulk"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetDepth(), int64(1))
+	assert.Equal(t, st.GetInFlight(), int64(1))
+	assert.NotNil(t, st.GetOldestAge())
+
+	_, err = cli.DeleteQueue(ctx, &pb.QueueDeleteRequest{Name: "bulk.dlq"})
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+	_, err = cli.DeleteQueue(ctx, &pb.QueueDeleteRequest{Name: "bulk"})
+	require.NoError(t, err)
+	_, err = cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "bulk.dlq"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+}
+
+func TestRestoreQueues(t *testing.T) {
+	dir := t.TempDir()
+	srv, err := newServer("q1=fifo", diskBackend{dir: dir})
+	require.NoError(t, err)
+	_, err = srv.CreateQueue(context.Background(), &pb.QueueCreateRequest{Name: "made", Config: &pb.QueueConfig{Order: pb.QueueOrder_LIFO, MaxAttempts: 1}})
+	require.NoError(t, err)
+	_, err = srv.AddTask(context.Background(), &pb.TaskAddRequest{Task: &pb.Task{Queue: "made", Name: "foo", Payload: text("bar")}})
+	require.NoError(t, err)
+	require.NoError(t, srv.queues.closeAll())
+
+	// queues created at runtime come back without being in the spec
+	srv, err = newServer("q2=fifo", diskBackend{dir: dir})
+	require.NoError(t, err)
+	defer srv.queues.closeAll()
+	names := []string{}
+	for _, q := range srv.queues.list() {
+		names = append(names, q.GetName())
+	}
+	assert.Equal(t, names, []string{"made", "made.dlq", "q1", "q2"})
+	q, _ := srv.queues.get("made")
+	k, v := q.Peep().KeyValue()
+	assert.Equal(t, k, "foo")
+	assert.Equal(t, v.GetText(), "bar")
+}
+
+func TestDelayedTask(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "both", NotBefore: timestamppb.Now(), Delay: durationpb.New(time.Second)}})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	added := time.Now()
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "later", Delay: durationpb.New(50 * time.Millisecond)}})
+	require.NoError(t, err)
+
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	assert.Nil(t, l.GetTask())
+	r, err := w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, r.GetTask().GetName(), "later")
+	assert.GreaterOrEqual(t, time.Since(added), 50*time.Millisecond)
+	assert.NotNil(t, r.GetTask().GetNotBefore())
+}
+
+func TestExpiringTask(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "both", ExpiresAt: timestamppb.Now(), Ttl: durationpb.New(time.Second)}})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "negative", Ttl: durationpb.New(-time.Second)}})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "stale", Ttl: durationpb.New(50 * time.Millisecond)}})
+	require.NoError(t, err)
+
+	r, err := w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, r.GetEvent(), pb.TaskEvent_ADDED)
+	assert.NotNil(t, r.GetTask().GetExpiresAt())
+	r, err = w.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, r.GetEvent(), pb.TaskEvent_EXPIRED)
+	assert.Equal(t, r.GetTask().GetName(), "stale")
+
+	p, err := cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "q1", MaxN: 1})
+	require.NoError(t, err)
+	assert.Empty(t, p.GetTasks())
+	st, err := cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "q1"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetExpired(), int64(1))
+}
+
+func TestBatchTasks(t *testing.T) {
+	cli := startServer(t, "q1=fifo,q2=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	r, err := cli.AddTasks(ctx, &pb.TasksAddRequest{Tasks: []*pb.Task{
+		{Queue: "q1", Name: "a", Payload: text("1")},
+		{Queue: "q1", Name: "a", Payload: text("2")},
+		{Queue: "q2", Name: "a", Payload: text("3")},
+		{Queue: "missing", Name: "b"},
+		{Queue: "q1", Name: "c", Payload: text("4")},
+	}})
+	require.NoError(t, err)
+	codesOf := []codes.Code{}
+	for _, res := range r.GetResults() {
+		codesOf = append(codesOf, codes.Code(res.GetCode()))
+	}
+	assert.Equal(t, codesOf, []codes.Code{codes.OK, codes.AlreadyExists, codes.OK, codes.InvalidArgument, codes.OK})
+
+	_, err = cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "q1"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	// peeking leaves the tasks queued
+	peeked, err := cli.PeekTasks(ctx, &pb.TasksPeekRequest{Queue: "q1", MaxN: 1})
+	require.NoError(t, err)
+	assert.Len(t, peeked.GetTasks(), 1)
+	assert.Equal(t, peeked.GetTasks()[0].GetPayload().GetText(), "1")
+	popped, err := cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "q1", MaxN: 10})
+	require.NoError(t, err)
+	payloads := []string{}
+	for _, t := range popped.GetTasks() {
+		payloads = append(payloads, t.GetPayload().GetText())
+	}
+	assert.Equal(t, payloads, []string{"1", "4"})
+}
+
+func TestAddTaskErrors(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "foo", Payload: text("bar")}})
+	require.NoError(t, err)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "foo", Payload: text("baz")}})
+	assert.Equal(t, status.Code(err), codes.AlreadyExists)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "foo", Payload: text("baz")}, Upsert: true})
+	require.NoError(t, err)
+
+	r, err := cli.RemoveTask(ctx, &pb.TaskRemoveRequest{Queue: "q1", Name: "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, r.GetTask().GetPayload().GetText(), "baz")
+	_, err = cli.RemoveTask(ctx, &pb.TaskRemoveRequest{Queue: "q1", Name: "foo"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: "q1/unknown"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+}
+
+func TestTypedPayloads(t *testing.T) {
+	dir := t.TempDir()
+	srv, err := newServer("q1=fifo", diskBackend{dir: dir})
+	require.NoError(t, err)
+	js, err := structpb.NewStruct(map[string]any{"user": "john", "retries": 3})
+	require.NoError(t, err)
+	msg, err := anypb.New(durationpb.New(time.Second))
+	require.NoError(t, err)
+	payloads := []*pb.Payload{
+		{Kind: &pb.Payload_Data{Data: []byte{0, 1, 2}}},
+		{Kind: &pb.Payload_Json{Json: js}},
+		{Kind: &pb.Payload_Proto{Proto: msg}},
+	}
+	for i, p := range payloads {
+		_, err := srv.AddTask(context.Background(), &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: fmt.Sprint(i), Payload: p}})
+		require.NoError(t, err)
+	}
+	// older clients only set the text field
+	_, err = srv.AddTask(context.Background(), &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "legacy", Text: "bar"}})
+	require.NoError(t, err)
+	require.NoError(t, srv.queues.closeAll())
+
+	srv, err = newServer("q1=fifo", diskBackend{dir: dir})
+	require.NoError(t, err)
+	defer srv.queues.closeAll()
+	r, err := srv.PopTasks(context.Background(), &pb.TasksPopRequest{Queue: "q1", MaxN: 10})
+	require.NoError(t, err)
+	require.Len(t, r.GetTasks(), 4)
+	for i, p := range payloads {
+		assert.True(t, proto.Equal(r.GetTasks()[i].GetPayload(), p), "payload %d: %v", i, r.GetTasks()[i].GetPayload())
+	}
+	legacy := r.GetTasks()[3]
+	assert.Equal(t, legacy.GetPayload().GetText(), "bar")
+	assert.Equal(t, legacy.GetText(), "bar")
+}
+
+func TestPayloadCodecLegacyValues(t *testing.T) {
+	p, err := payloadCodec{}.Unmarshal([]byte(`"bar"`))
+	require.NoError(t, err)
+	assert.Equal(t, p.GetText(), "bar")
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	st := status.Convert(err)
+	require.Equal(t, st.Code(), codes.ResourceExhausted, st.Message())
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration()
+		}
+	}
+	t.Fatalf("no retry info in %v", st)
+	return 0
+}
+
+func TestRateLimits(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "limited", Config: &pb.QueueConfig{
+		EnqueueRate: 1, EnqueueBurst: 3, ClientEnqueueRate: 1, ClientEnqueueBurst: 2, MaxInFlight: 1,
+	}})
+	require.NoError(t, err)
+	add := func(name string) error {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "limited", Name: name}})
+		return err
+	}
+	require.NoError(t, add("a1"))
+	require.NoError(t, add("a2"))
+	// the client is out of tokens while the queue still has one
+	d := retryAfter(t, add("a3"))
+	assert.Greater(t, d, time.Duration(0))
+	assert.LessOrEqual(t, d, time.Second)
+
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "limited"})
+	require.NoError(t, err)
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "limited"})
+	assert.Equal(t, retryAfter(t, err), inFlightRetryAfter)
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+	l, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "limited"})
+	require.NoError(t, err)
+	assert.Equal(t, l.GetTask().GetName(), "a2")
+
+	// other queues are not limited
+	for i := 0; i < 5; i++ {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: fmt.Sprint(i)}})
+		require.NoError(t, err)
+	}
+}
+
+func TestClientRateLimits(t *testing.T) {
+	l := newLimits(&pb.QueueConfig{EnqueueRate: 1, EnqueueBurst: 2, ClientEnqueueRate: 1, ClientEnqueueBurst: 1})
+	from := func(addr string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 1234}})
+	}
+	require.NoError(t, l.allowEnqueue(from("10.0.0.1"), "q"))
+	retryAfter(t, l.allowEnqueue(from("10.0.0.1"), "q"))
+	// claiming to be another client does not get around the limit
+	ctx := metadata.NewIncomingContext(from("10.0.0.1"), metadata.Pairs("x-client-id", "other"))
+	retryAfter(t, l.allowEnqueue(ctx, "q"))
+	require.NoError(t, l.allowEnqueue(from("10.0.0.2"), "q"))
+	// the queue is out of tokens for everyone
+	retryAfter(t, l.allowEnqueue(from("10.0.0.3"), "q"))
+}
+
+func TestOverflowPolicies(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "ring", Config: &pb.QueueConfig{MaxSize: 1, Overflow: pb.OverflowPolicy_DROP_OLDEST}})
+	require.NoError(t, err)
+	_, err = cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "bounded", Config: &pb.QueueConfig{MaxSize: 1, Overflow: pb.OverflowPolicy_BLOCK}})
+	require.NoError(t, err)
+	_, err = cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "bad", Config: &pb.QueueConfig{Overflow: pb.OverflowPolicy(42)}})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+
+	for _, name := range []string{"a", "b"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "ring", Name: name}})
+		require.NoError(t, err)
+	}
+	st, err := cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "ring"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetDepth(), int64(1))
+	assert.Equal(t, st.GetDropped(), int64(1))
+	assert.Equal(t, st.GetMaxSize(), int32(1))
+	assert.Equal(t, st.GetOverflow(), pb.OverflowPolicy_DROP_OLDEST)
+
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "bounded", Name: "a"}})
+	require.NoError(t, err)
+	short, cancelShort := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancelShort()
+	_, err = cli.AddTask(short, &pb.TaskAddRequest{Task: &pb.Task{Queue: "bounded", Name: "b"}})
+	assert.Equal(t, status.Code(err), codes.DeadlineExceeded)
+
+	added := make(chan error)
+	go func() {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "bounded", Name: "c"}})
+		added <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	popped, err := cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "bounded", MaxN: 1})
+	require.NoError(t, err)
+	assert.Equal(t, popped.GetTasks()[0].GetName(), "a")
+	require.NoError(t, <-added)
+	st, err = cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "bounded"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetDepth(), int64(1))
+	assert.Equal(t, st.GetRejected(), int64(1))
+}
+
+func TestDedupWindow(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// results are kept for the dedup window even though the queue does not keep them otherwise
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "once", Config: &pb.QueueConfig{DedupWindow: durationpb.New(time.Minute), ResultTtl: durationpb.New(0)}})
+	require.NoError(t, err)
+	add := func() *pb.TaskAddReply {
+		r, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "once", Name: "foo", Payload: text("bar")}})
+		require.NoError(t, err)
+		return r
+	}
+	first := add()
+	assert.False(t, first.GetDuplicate())
+	// retried while the task is still queued
+	r := add()
+	assert.True(t, r.GetDuplicate())
+	assert.Equal(t, r.GetMsg(), first.GetMsg())
+	assert.Nil(t, first.GetOriginal())
+	assert.Equal(t, r.GetOriginal().GetState(), pb.TaskState_PENDING)
+
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "once"})
+	require.NoError(t, err)
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId(), Result: []byte("done")})
+	require.NoError(t, err)
+	// retried after the task was processed, getting its result back
+	r = add()
+	assert.True(t, r.GetDuplicate())
+	assert.Equal(t, r.GetOriginal().GetState(), pb.TaskState_SUCCEEDED)
+	assert.Equal(t, r.GetOriginal().GetResult(), []byte("done"))
+	assert.Equal(t, r.GetOriginal().GetAttempts(), int32(1))
+	batch, err := cli.AddTasks(ctx, &pb.TasksAddRequest{Tasks: []*pb.Task{{Queue: "once", Name: "foo"}, {Queue: "once", Name: "bar"}}})
+	require.NoError(t, err)
+	assert.True(t, batch.GetResults()[0].GetDuplicate())
+	assert.Equal(t, codes.Code(batch.GetResults()[0].GetCode()), codes.OK)
+	assert.Equal(t, batch.GetResults()[0].GetOriginal().GetResult(), []byte("done"))
+	assert.False(t, batch.GetResults()[1].GetDuplicate())
+	assert.Nil(t, batch.GetResults()[1].GetOriginal())
+
+	st, err := cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "once"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetDepth(), int64(1))
+}
+
+func TestDeadLetters(t *testing.T) {
+	cli := startServer(t, "q1=fifo:1,q2=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, name := range []string{"foo", "bar"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name, Payload: text(name)}})
+		require.NoError(t, err)
+	}
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = cli.NackTask(ctx, &pb.TaskNackRequest{LeaseId: l.GetLeaseId(), Error: "boom"})
+	require.NoError(t, err)
+
+	list, err := cli.ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "q1"})
+	require.NoError(t, err)
+	require.Len(t, list.GetTasks(), 1)
+	assert.Equal(t, list.GetTasks()[0].GetName(), "foo")
+	assert.Equal(t, list.GetTasks()[0].GetQueue(), "q1.dlq")
+	dead, err := cli.GetDeadLetter(ctx, &pb.DeadLetterGetRequest{Queue: "q1", Name: "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, dead.GetTask().GetAttempts(), int32(1))
+	assert.Equal(t, dead.GetTask().GetPayload().GetText(), "foo")
+	_, err = cli.GetDeadLetter(ctx, &pb.DeadLetterGetRequest{Queue: "q1", Name: "bar"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+	_, err = cli.ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "q2"})
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+	replayed, err := cli.ReplayDeadLetters(ctx, &pb.DeadLetterReplayRequest{Queue: "q1", Names: []string{"foo", "missing"}})
+	require.NoError(t, err)
+	assert.Equal(t, replayed.GetNames(), []string{"foo"})
+	list, err = cli.ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "q1"})
+	require.NoError(t, err)
+	assert.Empty(t, list.GetTasks())
+	// replayed tasks go to the back of the queue with their attempts reset
+	popped, err := cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "q1", MaxN: 2})
+	require.NoError(t, err)
+	require.Len(t, popped.GetTasks(), 2)
+	assert.Equal(t, popped.GetTasks()[1].GetName(), "foo")
+	assert.Equal(t, popped.GetTasks()[1].GetAttempts(), int32(0))
+}
+
+func TestGetTask(t *testing.T) {
+	cli := startServer(t, "q1=fifo:1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range []string{"good", "bad"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name}})
+		require.NoError(t, err)
+	}
+	get := func(name string) *pb.TaskGetReply {
+		r, err := cli.GetTask(ctx, &pb.TaskGetRequest{Queue: "q1", Name: name})
+		require.NoError(t, err)
+		return r
+	}
+	assert.Equal(t, get("good").GetState(), pb.TaskState_PENDING)
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	assert.Equal(t, get("good").GetState(), pb.TaskState_LEASED)
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId(), Result: []byte("done")})
+	require.NoError(t, err)
+	good := get("good")
+	assert.Equal(t, good.GetState(), pb.TaskState_SUCCEEDED)
+	assert.Equal(t, good.GetResult(), []byte("done"))
+	assert.Equal(t, good.GetAttempts(), int32(1))
+	assert.NotNil(t, good.GetDoneAt())
+
+	l, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = cli.NackTask(ctx, &pb.TaskNackRequest{LeaseId: l.GetLeaseId(), Error: "upstream is down"})
+	require.NoError(t, err)
+	bad := get("bad")
+	assert.Equal(t, bad.GetState(), pb.TaskState_DEAD)
+	assert.Equal(t, bad.GetLastError(), "upstream is down")
+
+	_, err = cli.GetTask(ctx, &pb.TaskGetRequest{Queue: "q1", Name: "missing"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+}
+
+func TestConsumerGroups(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "orders", Config: &pb.QueueConfig{ConsumerGroups: []string{"billing", "billing"}}})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	_, err = cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "orders", Config: &pb.QueueConfig{ConsumerGroups: []string{"billing", "analytics"}, MaxAttempts: 1}})
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "orders", Name: name, Payload: text(name)}})
+		require.NoError(t, err)
+	}
+
+	// every group gets every task, workers of a group share them
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "orders", Group: "billing"})
+	require.NoError(t, err)
+	assert.Equal(t, l.GetTask().GetName(), "a")
+	assert.Equal(t, l.GetTask().GetQueue(), "orders")
+	l2, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "orders", Group: "billing"})
+	require.NoError(t, err)
+	assert.Equal(t, l2.GetTask().GetName(), "b")
+	stats, err := cli.GetQueueStats(ctx, &pb.QueueStatsRequest{Name: "orders"})
+	require.NoError(t, err)
+	assert.Equal(t, stats.GetDepth(), int64(2))
+	assert.Equal(t, stats.GetInFlight(), int64(2))
+	assert.NotNil(t, stats.GetOldestAge())
+	pop, err := cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "orders", Group: "analytics", MaxN: 5})
+	require.NoError(t, err)
+	assert.Len(t, pop.GetTasks(), 2)
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+	_, err = cli.NackTask(ctx, &pb.TaskNackRequest{LeaseId: l2.GetLeaseId()})
+	require.NoError(t, err)
+	st, err := cli.GetTask(ctx, &pb.TaskGetRequest{Queue: "orders", Group: "billing", Name: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetState(), pb.TaskState_SUCCEEDED)
+	dead, err := cli.ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "orders", Group: "billing"})
+	require.NoError(t, err)
+	assert.Len(t, dead.GetTasks(), 1)
+	dead, err = cli.ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "orders", Group: "analytics"})
+	require.NoError(t, err)
+	assert.Len(t, dead.GetTasks(), 0)
+
+	// removing without a group removes from every group holding the task
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "orders", Name: "c"}})
+	require.NoError(t, err)
+	_, err = cli.RemoveTask(ctx, &pb.TaskRemoveRequest{Queue: "orders", Name: "c", Group: "billing"})
+	require.NoError(t, err)
+	_, err = cli.RemoveTask(ctx, &pb.TaskRemoveRequest{Queue: "orders", Name: "c"})
+	require.NoError(t, err)
+	_, err = cli.RemoveTask(ctx, &pb.TaskRemoveRequest{Queue: "orders", Name: "c"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "orders"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "orders", Group: "shipping"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1", Group: "billing"})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	_, err = cli.DeleteQueue(ctx, &pb.QueueDeleteRequest{Name: "orders@billing"})
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+	_, err = cli.DeleteQueue(ctx, &pb.QueueDeleteRequest{Name: "orders"})
+	require.NoError(t, err)
+	list, err := cli.ListQueues(ctx, &pb.QueueListRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetQueues(), 1)
+}
+
+func TestConsumerGroupRollback(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "orders", Config: &pb.QueueConfig{ConsumerGroups: []string{"billing", "analytics"}, MaxSize: 2}})
+	require.NoError(t, err)
+	add := func(name, payload string, upsert bool) error {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "orders", Name: name, Payload: text(payload)}, Upsert: upsert})
+		return err
+	}
+	remove := func(name, group string) {
+		_, err := cli.RemoveTask(ctx, &pb.TaskRemoveRequest{Queue: "orders", Name: name, Group: group})
+		require.NoError(t, err)
+	}
+	peek := func(group string) map[string]string {
+		r, err := cli.PeekTasks(ctx, &pb.TasksPeekRequest{Queue: "orders", Group: group, MaxN: 5})
+		require.NoError(t, err)
+		tasks := map[string]string{}
+		for _, t := range r.GetTasks() {
+			tasks[t.GetName()] = t.GetPayload().GetText()
+		}
+		return tasks
+	}
+	// billing ends up with room and foo while analytics is full without it
+	require.NoError(t, add("foo", "old", false))
+	remove("foo", "analytics")
+	require.NoError(t, add("bar", "bar", false))
+	remove("bar", "billing")
+	require.NoError(t, add("baz", "baz", false))
+	remove("baz", "billing")
+	assert.Equal(t, peek("billing"), map[string]string{"foo": "old"})
+	assert.Equal(t, peek("analytics"), map[string]string{"bar": "bar", "baz": "baz"})
+
+	// upserts refused by a group are undone in the groups that took them, whether they replaced
+	// the content of a task or added it
+	assert.Equal(t, status.Code(add("foo", "new", true)), codes.ResourceExhausted)
+	assert.Equal(t, status.Code(add("fizz", "new", true)), codes.ResourceExhausted)
+	assert.Equal(t, peek("billing"), map[string]string{"foo": "old"})
+	assert.Equal(t, peek("analytics"), map[string]string{"bar": "bar", "baz": "baz"})
+}
+
+func TestReservedQueueNames(t *testing.T) {
+	cli := startServer(t, "q1=fifo:1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "orders", Config: &pb.QueueConfig{ConsumerGroups: []string{"billing"}}})
+	require.NoError(t, err)
+
+	// dead letter and consumer group queues are only reached through their queue
+	for _, name := range []string{"q1.dlq", "orders@billing"} {
+		_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: name, Name: "foo"}})
+		assert.Equal(t, status.Code(err), codes.InvalidArgument, name)
+		_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: name})
+		assert.Equal(t, status.Code(err), codes.InvalidArgument, name)
+		_, err = cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: name, MaxN: 1})
+		assert.Equal(t, status.Code(err), codes.InvalidArgument, name)
+	}
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "orders", Name: "foo"}})
+	require.NoError(t, err)
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "orders", Group: "billing"})
+	require.NoError(t, err)
+	// the acl checks the queue the lease is acked on
+	_, names, _ := access(&pb.TaskAckRequest{LeaseId: l.GetLeaseId()})
+	assert.Equal(t, names, []string{"orders@billing"})
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+}
+
+func TestMetrics(t *testing.T) {
+	cli, reg := startMetricsServer(t, "q1=fifo:1")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	w, err := cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name, Payload: text(name)}})
+		require.NoError(t, err)
+	}
+	_, err = cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "q1", MaxN: 1})
+	require.NoError(t, err)
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1", LeaseDuration: durationpb.New(10 * time.Millisecond)})
+	require.NoError(t, err)
+	// the expired lease used up the only attempt so the task is dead lettered
+	assert.Eventually(t, func() bool {
+		r, err := cli.GetTask(ctx, &pb.TaskGetRequest{Queue: "q1", Name: "b"})
+		return err == nil && r.GetState() == pb.TaskState_DEAD
+	}, time.Second, 5*time.Millisecond)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "missing", Name: "a"}})
+	require.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, line := range []string{
+		`queue_workers_queue_depth{queue="q1"} 1`,
+		`queue_workers_queue_in_flight{queue="q1"} 0`,
+		`queue_workers_watch_subscribers{queue="q1"} 1`,
+		`queue_workers_tasks_enqueued_total{queue="q1"} 3`,
+		`queue_workers_tasks_dequeued_total{queue="q1"} 2`,
+		`queue_workers_lease_expirations_total{queue="q1"} 1`,
+		`queue_workers_tasks_dead_lettered_total{queue="q1"} 1`,
+		`queue_workers_tasks_enqueued_total{queue="q1.dlq"} 1`,
+		`queue_workers_rpc_duration_seconds_count{code="OK",method="/queue.Queue/AddTask"} 3`,
+		`queue_workers_rpc_duration_seconds_count{code="InvalidArgument",method="/queue.Queue/AddTask"} 1`,
+	} {
+		assert.Contains(t, body, line)
+	}
+}
+
+// authorized returns a context authenticating the client with token
+func authorized(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+}
+
+func TestACL(t *testing.T) {
+	srv, err := newServer("billing-in=fifo:1,billing-out=fifo,other=fifo", memoryBackend{})
+	require.NoError(t, err)
+	srv.acl, err = newACL(aclConfig{
+		Tokens: map[string]string{"p": "producer", "c": "consumer", "a": "admin"},
+		Rules: []aclRule{
+			{Identity: "producer", Queues: []string{"billing-*"}, Ops: []op{opProduce}},
+			{Identity: "consumer", Queues: []string{"billing-in"}, Ops: []op{opConsume}},
+			{Identity: "admin", Queues: []string{"*"}, Ops: []op{opAdmin}},
+		},
+	})
+	require.NoError(t, err)
+	cli, _, _ := serve(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	producer, consumer, admin := authorized(ctx, "p"), authorized(ctx, "c"), authorized(ctx, "a")
+
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "billing-in", Name: "a"}})
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	_, err = cli.AddTask(authorized(ctx, "nope"), &pb.TaskAddRequest{Task: &pb.Task{Queue: "billing-in", Name: "a"}})
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	_, err = cli.AddTask(producer, &pb.TaskAddRequest{Task: &pb.Task{Queue: "billing-in", Name: "a"}})
+	require.NoError(t, err)
+	_, err = cli.AddTask(producer, &pb.TaskAddRequest{Task: &pb.Task{Queue: "other", Name: "a"}})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = cli.AddTasks(producer, &pb.TasksAddRequest{Tasks: []*pb.Task{{Queue: "billing-out", Name: "a"}, {Queue: "other", Name: "a"}}})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = cli.AddTask(consumer, &pb.TaskAddRequest{Task: &pb.Task{Queue: "billing-in", Name: "b"}})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+
+	_, err = cli.LeaseTask(producer, &pb.TaskLeaseRequest{Queue: "billing-in"})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	l, err := cli.LeaseTask(consumer, &pb.TaskLeaseRequest{Queue: "billing-in"})
+	require.NoError(t, err)
+	_, err = cli.NackTask(producer, &pb.TaskNackRequest{LeaseId: l.GetLeaseId()})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = cli.NackTask(consumer, &pb.TaskNackRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+	// dead letter queues go by the rules of their queue
+	dead, err := cli.ListDeadLetters(consumer, &pb.DeadLetterListRequest{Queue: "billing-in"})
+	require.NoError(t, err)
+	assert.Len(t, dead.GetTasks(), 1)
+	st, err := cli.GetTask(producer, &pb.TaskGetRequest{Queue: "billing-in", Name: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetState(), pb.TaskState_DEAD)
+
+	w, err := cli.WatchQueue(producer, &pb.TaskWatchRequest{Queue: "billing-in"})
+	require.NoError(t, err)
+	_, err = w.Recv()
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+
+	list, err := cli.ListQueues(consumer, &pb.QueueListRequest{})
+	require.NoError(t, err)
+	names := []string{}
+	for _, q := range list.GetQueues() {
+		names = append(names, q.GetName())
+	}
+	assert.Equal(t, names, []string{"billing-in", "billing-in.dlq"})
+	_, err = cli.CreateQueue(producer, &pb.QueueCreateRequest{Name: "billing-new"})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = cli.CreateQueue(admin, &pb.QueueCreateRequest{Name: "billing-new"})
+	require.NoError(t, err)
+	_, err = cli.Drain(producer, &pb.DrainRequest{})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = cli.Drain(admin, &pb.DrainRequest{})
+	require.NoError(t, err)
+}
+
+func TestACLRateLimits(t *testing.T) {
+	srv, err := newServer("q1=fifo", memoryBackend{})
+	require.NoError(t, err)
+	srv.acl, err = newACL(aclConfig{
+		Tokens: map[string]string{"a": "alice", "b": "bob"},
+		Rules:  []aclRule{{Identity: "*", Queues: []string{"*"}, Ops: []op{opProduce, opAdmin}}},
+	})
+	require.NoError(t, err)
+	cli, _, _ := serve(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	alice, bob := authorized(ctx, "a"), authorized(ctx, "b")
+	_, err = cli.CreateQueue(alice, &pb.QueueCreateRequest{Name: "limited", Config: &pb.QueueConfig{ClientEnqueueRate: 1, ClientEnqueueBurst: 1}})
+	require.NoError(t, err)
+	add := func(ctx context.Context, name string) error {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "limited", Name: name}})
+		return err
+	}
+
+	// clients sharing a connection are limited by the identity they authenticated with
+	require.NoError(t, add(alice, "a1"))
+	retryAfter(t, add(alice, "a2"))
+	retryAfter(t, add(metadata.AppendToOutgoingContext(alice, "x-client-id", "other"), "a3"))
+	require.NoError(t, add(bob, "b1"))
+}
+
+func TestACLClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := newCert(t, "ca", nil, nil)
+	srvCert, srvKey := newCert(t, "bufnet", ca, caKey)
+	cliCert, cliKey := newCert(t, "worker", ca, caKey)
+	write := func(name string, block *pem.Block) string {
+		f := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(f, pem.EncodeToMemory(block), 0o600))
+		return f
+	}
+	caFile := write("ca.pem", &pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})
+	conf, err := serverTLS(write("srv.pem", &pem.Block{Type: "CERTIFICATE", Bytes: srvCert.Raw}), write("srv.key", keyPEM(t, srvKey)), caFile)
+	require.NoError(t, err)
+
+	srv, err := newServer("q1=fifo", memoryBackend{})
+	require.NoError(t, err)
+	srv.acl, err = newACL(aclConfig{Rules: []aclRule{{Identity: "worker", Queues: []string{"q1"}, Ops: []op{opConsume}}}})
+	require.NoError(t, err)
+	lis := bufconn.Listen(1 << 20)
+	grpcServ := newGRPCServer(srv, newMetrics(srv.queues), grpc.Creds(credentials.NewTLS(conf)))
+	go grpcServ.Serve(lis)
+	t.Cleanup(grpcServ.Stop)
+	dial := func(certs ...tls.Certificate) pb.QueueClient {
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		creds := credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "bufnet", Certificates: certs})
+		conn, err := grpc.Dial("bufnet",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(creds),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewQueueClient(conn)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	pair, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cliCert.Raw}), pem.EncodeToMemory(keyPEM(t, cliKey)))
+	require.NoError(t, err)
+	_, err = dial(pair).LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = dial(pair).AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "a"}})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = dial().LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
+
+// newCert issues a certificate for cn signed by parent, a self signed ca when parent is nil
+func newCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		DNSNames:     []string{cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func keyPEM(t *testing.T, key *ecdsa.PrivateKey) *pem.Block {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+}
+
+func TestDrain(t *testing.T) {
+	cli := startServer(t, "q1=fifo")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range []string{"a", "b"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name}})
+		require.NoError(t, err)
+	}
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	w, err := cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+
+	r, err := cli.Drain(ctx, &pb.DrainRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, r.GetDepth(), int64(1))
+	assert.Equal(t, r.GetInFlight(), int64(1))
+	_, err = w.Recv()
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "c"}})
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+	w, err = cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Recv()
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+
+	// work in progress can still be finished
+	_, err = cli.AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+	r, err = cli.Drain(ctx, &pb.DrainRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, r.GetInFlight(), int64(0))
+}
+
+func TestShutdown(t *testing.T) {
+	dir := t.TempDir()
+	srv, err := newServer("q1=fifo", diskBackend{dir: dir})
+	require.NoError(t, err)
+	cli, grpcServ, _ := serve(t, srv)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, name := range []string{"a", "b"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name}})
+		require.NoError(t, err)
+	}
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+	w, err := cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: "q1"})
+	require.NoError(t, err)
+	_, err = w.Header()
+	require.NoError(t, err)
+
+	// the open watch does not hold up the shutdown
+	start := time.Now()
+	require.NoError(t, srv.shutdown(grpcServ, 5*time.Second))
+	assert.Less(t, time.Since(start), time.Second)
+	_, err = w.Recv()
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+
+	// the leased task comes back along with the queued one
+	srv, err = newServer("q1=fifo", diskBackend{dir: dir})
+	require.NoError(t, err)
+	defer srv.queues.closeAll()
+	q, _ := srv.queues.get("q1")
+	assert.Equal(t, q.Stats().Depth, 2)
+}
+
+func TestRedisBackend(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	srv, err := newServer("q1=fifo", redisBackend{rdb: rdb})
+	require.NoError(t, err)
+	defer srv.queues.closeAll()
+	a, _, _ := serve(t, srv)
+	_, err = a.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "made", Config: &pb.QueueConfig{MaxAttempts: 1}})
+	require.NoError(t, err)
+	_, err = a.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "made", Name: "foo", Payload: text("bar")}})
+	require.NoError(t, err)
+
+	// a second server sharing the redis serves the same queues and tasks
+	srv, err = newServer("q1=fifo", redisBackend{rdb: rdb})
+	require.NoError(t, err)
+	defer srv.queues.closeAll()
+	b, _, _ := serve(t, srv)
+	_, err = b.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "made", Name: "foo"}})
+	assert.Equal(t, status.Code(err), codes.AlreadyExists)
+	l, err := b.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "made"})
+	require.NoError(t, err)
+	assert.Equal(t, l.GetTask().GetPayload().GetText(), "bar")
+	_, err = a.NackTask(ctx, &pb.TaskNackRequest{LeaseId: l.GetLeaseId(), Error: "boom"})
+	require.NoError(t, err)
+	r, err := b.ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "made"})
+	require.NoError(t, err)
+	assert.Len(t, r.GetTasks(), 1)
+	st, err := b.GetTask(ctx, &pb.TaskGetRequest{Queue: "made", Name: "foo"})
+	require.NoError(t, err)
+	assert.Equal(t, st.GetState(), pb.TaskState_DEAD)
+	assert.Equal(t, st.GetLastError(), "boom")
+
+	_, err = b.DeleteQueue(ctx, &pb.QueueDeleteRequest{Name: "made"})
+	require.NoError(t, err)
+	for _, k := range mr.Keys() {
+		assert.NotContains(t, k, "made")
+	}
+}
+
+// downBackend replicates queues on a log that lost its leader, as raft does during an election
+type downBackend struct {
+	memoryBackend
+}
+
+func (downBackend) open(name string, opts ...queue.Option) (taskQueue, error) {
+	return queue.NewReplicated[string](downLog{}, name, payloadCodec{}, opts...), nil
+}
+
+type downLog struct{}
+
+func (downLog) Append(name string, cmd []byte) (any, error) {
+	return nil, status.Error(codes.Unavailable, "no raft leader is elected")
+}
+
+func TestLeaderLost(t *testing.T) {
+	srv, err := newServer("q1=fifo", downBackend{})
+	require.NoError(t, err)
+	defer srv.queues.closeAll()
+	cli, _, _ := serve(t, srv)
+	ctx := context.Background()
+
+	// clients are told to retry rather than that the queue is empty
+	_, err = cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+	_, err = cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "q1", MaxN: 1})
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: "foo"}})
+	assert.Equal(t, status.Code(err), codes.Unavailable)
+}
+
+// startCluster starts n servers of a raft cluster over loopback, returning their clients
+func startCluster(t *testing.T, spec string, n int) ([]pb.QueueClient, []*server) {
+	t.Helper()
+	peers := []raftPeer{}
+	transports := []raft.Transport{}
+	listeners := []net.Listener{}
+	for i := 0; i < n; i++ {
+		trans, err := raft.NewTCPTransport("127.0.0.1:0", nil, 3, time.Second, io.Discard)
+		require.NoError(t, err)
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		peers = append(peers, raftPeer{id: fmt.Sprintf("n%d", i), raftAddr: string(trans.LocalAddr()), grpcAddr: lis.Addr().String()})
+		transports = append(transports, trans)
+		listeners = append(listeners, lis)
+	}
+	clis := []pb.QueueClient{}
+	srvs := []*server{}
+	for i := 0; i < n; i++ {
+		srv, err := newServer(spec, raftBackend{node: newRaftNode(peers[i].id, peers, "", transports[i])})
+		require.NoError(t, err)
+		grpcServ := newGRPCServer(srv, newMetrics(srv.queues))
+		go grpcServ.Serve(listeners[i])
+		t.Cleanup(func() {
+			grpcServ.Stop()
+			srv.cluster.close()
+			srv.queues.closeAll()
+		})
+		conn, err := grpc.Dial(peers[i].grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		clis = append(clis, pb.NewQueueClient(conn))
+		srvs = append(srvs, srv)
+	}
+	return clis, srvs
+}
+
+// waitLeader returns which of the servers at the indexes given gets elected leader,
+// once every one of them knows it is
+func waitLeader(t *testing.T, srvs []*server, among ...int) int {
+	t.Helper()
+	leader := -1
+	require.Eventually(t, func() bool {
+		leader = -1
+		for _, i := range among {
+			if srvs[i].cluster.isLeader() {
+				leader = i
+			}
+		}
+		if leader < 0 {
+			return false
+		}
+		for _, i := range among {
+			if _, id := srvs[i].cluster.raft.LeaderWithID(); string(id) != srvs[leader].cluster.id {
+				return false
+			}
+		}
+		return true
+	}, 10*time.Second, 20*time.Millisecond)
+	return leader
+}
+
+func TestRaftCluster(t *testing.T) {
+	clis, srvs := startCluster(t, "q1=fifo", 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	leader := waitLeader(t, srvs, 0, 1, 2)
+	follower := (leader + 1) % 3
+
+	// changes made through a follower are forwarded to the leader and replicated to every server
+	cli := clis[follower]
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "made", Config: &pb.QueueConfig{MaxAttempts: 1}})
+	require.NoError(t, err)
+	for _, name := range []string{"foo", "john"} {
+		_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "made", Name: name, Payload: text(name)}})
+		require.NoError(t, err)
+	}
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "made", Name: "foo"}})
+	assert.Equal(t, status.Code(err), codes.AlreadyExists)
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "made"})
+	require.NoError(t, err)
+	assert.Equal(t, l.GetTask().GetName(), "foo")
+	for _, c := range clis {
+		assert.Eventually(t, func() bool {
+			r, err := c.GetTask(ctx, &pb.TaskGetRequest{Queue: "made", Name: "foo"})
+			return err == nil && r.GetState() == pb.TaskState_LEASED
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+	_, err = clis[leader].NackTask(ctx, &pb.TaskNackRequest{LeaseId: l.GetLeaseId(), Error: "boom"})
+	require.NoError(t, err)
+
+	// the cluster carries on once the leader is gone
+	require.NoError(t, srvs[leader].cluster.raft.Shutdown().Error())
+	survivors := []int{}
+	for i := range srvs {
+		if i != leader {
+			survivors = append(survivors, i)
+		}
+	}
+	next := waitLeader(t, srvs, survivors...)
+	cli = clis[survivors[0]]
+	if survivors[0] == next {
+		cli = clis[survivors[1]]
+	}
+	r, err := cli.PopTasks(ctx, &pb.TasksPopRequest{Queue: "made", MaxN: 10})
+	require.NoError(t, err)
+	require.Len(t, r.GetTasks(), 1)
+	assert.Equal(t, r.GetTasks()[0].GetPayload().GetText(), "john")
+	for _, i := range survivors {
+		assert.Eventually(t, func() bool {
+			r, err := clis[i].ListDeadLetters(ctx, &pb.DeadLetterListRequest{Queue: "made"})
+			return err == nil && len(r.GetTasks()) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+}
+
+func TestRaftForwardedPeer(t *testing.T) {
+	n := newRaftNode("n0", []raftPeer{{id: "n0", raftAddr: "127.0.0.1:7000", grpcAddr: "127.0.0.1:8000"}}, "", nil)
+	from := func(addr string, md metadata.MD) context.Context {
+		host, port, _ := net.SplitHostPort(addr)
+		p, _ := strconv.Atoi(port)
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(host), Port: p}})
+		return metadata.NewIncomingContext(ctx, md)
+	}
+	forwarded := metadata.Pairs(forwardedKey, "n1", forwardedForKey, "10.0.0.7:5000")
+
+	// the leader limits the client behind the follower, not the follower
+	assert.Equal(t, clientID(n.forwardedPeer(from("127.0.0.1:6000", forwarded))), "10.0.0.7")
+	// clients claiming to forward rpcs are limited by their own address
+	assert.Equal(t, clientID(n.forwardedPeer(from("10.0.0.9:6000", forwarded))), "10.0.0.9")
+	assert.Equal(t, clientID(n.forwardedPeer(from("127.0.0.1:6000", metadata.MD{}))), "127.0.0.1")
+}
+
+func TestRaftSnapshot(t *testing.T) {
+	clis, srvs := startCluster(t, "q1=fifo:1", 3)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	leader := waitLeader(t, srvs, 0, 1, 2)
+	cli := clis[leader]
+	for _, name := range []string{"foo", "bar", "baz"} {
+		_, err := cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "q1", Name: name, Payload: text(name)}})
+		require.NoError(t, err)
+	}
+	_, err := cli.CreateQueue(ctx, &pb.QueueCreateRequest{Name: "made", Config: &pb.QueueConfig{Order: pb.QueueOrder_LIFO, ConsumerGroups: []string{"g"}}})
+	require.NoError(t, err)
+	_, err = cli.AddTask(ctx, &pb.TaskAddRequest{Task: &pb.Task{Queue: "made", Name: "foo"}})
+	require.NoError(t, err)
+	l, err := cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: "q1"})
+	require.NoError(t, err)
+
+	// a registry restored from the snapshot holds the same queues, leases included
+	f := srvs[leader].cluster.raft.Snapshot()
+	require.NoError(t, f.Error())
+	_, rc, err := f.Open()
+	require.NoError(t, err)
+	reg := newRegistry(raftBackend{node: newRaftNode("restored", nil, "", nil)})
+	defer reg.closeAll()
+	require.NoError(t, (&raftFSM{queues: reg}).Restore(rc))
+	names := []string{}
+	for _, info := range reg.list() {
+		names = append(names, info.GetName())
+	}
+	assert.Equal(t, names, []string{"made", "made@g", "q1", "q1.dlq"})
+	e, _ := reg.lookup("made")
+	assert.Equal(t, e.config.GetOrder(), pb.QueueOrder_LIFO)
+	g, _ := reg.get("made@g")
+	assert.NotNil(t, g.Get("foo"))
+	q, _ := reg.get("q1")
+	st, _ := q.Status("foo")
+	assert.Equal(t, st.State, queue.Leased)
+	assert.Equal(t, q.Stats().Depth, 2)
+	_, err = clis[leader].AckTask(ctx, &pb.TaskAckRequest{LeaseId: l.GetLeaseId()})
+	require.NoError(t, err)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+const metricsNamespace = "queue_workers"
+
+func queueDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "", name), help, []string{"queue"}, nil)
+}
+
+var (
+	depthDesc       = queueDesc("queue_depth", "Tasks waiting to be handed out.")
+	inFlightDesc    = queueDesc("queue_in_flight", "Tasks leased and not settled yet.")
+	delayedDesc     = queueDesc("queue_delayed", "Tasks held back until their not before time.")
+	subscribersDesc = queueDesc("watch_subscribers", "Open watches on the queue.")
+	enqueuedDesc    = queueDesc("tasks_enqueued_total", "Tasks added to the queue.")
+	dequeuedDesc    = queueDesc("tasks_dequeued_total", "Tasks handed out by pops and leases.")
+	expiredDesc     = queueDesc("lease_expirations_total", "Leases that ran out before being acked or nacked.")
+	deadDesc        = queueDesc("tasks_dead_lettered_total", "Tasks moved to the dead letter queue after running out of attempts.")
+	droppedDesc     = queueDesc("tasks_dropped_total", "Tasks discarded to make room by the drop_oldest overflow policy.")
+	rejectedDesc    = queueDesc("adds_rejected_total", "Adds that failed because the queue was full.")
+	staleDesc       = queueDesc("tasks_expired_total", "Tasks discarded for outliving their expiry or the ttl of the queue.")
+)
+
+// metrics exports the stats of the served queues and the latency of the rpcs,
+// queue stats are read on scrape so they cost nothing between scrapes
+type metrics struct {
+	queues  *registry
+	latency *prometheus.HistogramVec
+}
+
+func newMetrics(queues *registry) *metrics {
+	return &metrics{
+		queues: queues,
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "rpc_duration_seconds",
+			Help:      "Time taken to handle rpcs, streams are observed when they end.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+}
+
+func (m *metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{depthDesc, inFlightDesc, delayedDesc, subscribersDesc,
+		enqueuedDesc, dequeuedDesc, expiredDesc, deadDesc, droppedDesc, rejectedDesc, staleDesc} {
+		ch <- d
+	}
+	m.latency.Describe(ch)
+}
+
+// Collect reports counters as kept by the queues, they start over when a queue is
+// recreated or the server restarts which prometheus handles as a counter reset
+func (m *metrics) Collect(ch chan<- prometheus.Metric) {
+	m.queues.each(func(name string, e *entry) {
+		st := e.q.Stats()
+		for _, v := range []struct {
+			desc  *prometheus.Desc
+			kind  prometheus.ValueType
+			value int
+		}{
+			{depthDesc, prometheus.GaugeValue, st.Depth},
+			{inFlightDesc, prometheus.GaugeValue, st.InFlight},
+			{delayedDesc, prometheus.GaugeValue, st.Delayed},
+			{subscribersDesc, prometheus.GaugeValue, e.events.numWatchers()},
+			{enqueuedDesc, prometheus.CounterValue, st.Added},
+			{dequeuedDesc, prometheus.CounterValue, st.Delivered},
+			{expiredDesc, prometheus.CounterValue, st.LeasesExpired},
+			{deadDesc, prometheus.CounterValue, st.DeadLettered},
+			{droppedDesc, prometheus.CounterValue, st.Dropped},
+			{rejectedDesc, prometheus.CounterValue, st.Rejected},
+			{staleDesc, prometheus.CounterValue, st.Expired},
+		} {
+			ch <- prometheus.MustNewConstMetric(v.desc, v.kind, float64(v.value), name)
+		}
+	})
+	m.latency.Collect(ch)
+}
+
+func (m *metrics) observe(method string, start time.Time, err error) {
+	m.latency.WithLabelValues(method, status.Code(err).String()).Observe(time.Since(start).Seconds())
+}
+
+func (m *metrics) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	m.observe(info.FullMethod, start, err)
+	return resp, err
+}
+
+func (m *metrics) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	m.observe(info.FullMethod, start, err)
+	return err
+}
+
+// newMetricsRegistry registers m along with the go runtime and process collectors
+func newMetricsRegistry(m *metrics) *prometheus.Registry {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(m, collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return reg
+}
+
+// serveMetrics exposes reg at /metrics on addr in the background
+func serveMetrics(addr string, reg *prometheus.Registry) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	serv := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		log.Printf("metrics listening at %s", addr)
+		if err := serv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed to serve metrics: %s", err)
+		}
+	}()
+	return serv
+}
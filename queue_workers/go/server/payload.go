@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// taskQueue holds task payloads under their task name
+type taskQueue = queue.Queue[string, *pb.Payload]
+
+// payloadOf reads the payload of t, falling back to the text field set by older clients
+func payloadOf(t *pb.Task) *pb.Payload {
+	if p := t.GetPayload(); p != nil {
+		return p
+	}
+	//lint:ignore SA1019 older clients only know about the text field
+	return &pb.Payload{Kind: &pb.Payload_Text{Text: t.GetText()}}
+}
+
+// payloadCodec persists payloads as protojson
+type payloadCodec struct{}
+
+func (payloadCodec) Marshal(p *pb.Payload) ([]byte, error) {
+	return protojson.Marshal(p)
+}
+
+func (payloadCodec) Unmarshal(b []byte) (*pb.Payload, error) {
+	// queues persisted before payloads were typed hold plain JSON strings
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		var text string
+		if err := json.Unmarshal(b, &text); err != nil {
+			return nil, err
+		}
+		return &pb.Payload{Kind: &pb.Payload_Text{Text: text}}, nil
+	}
+	p := &pb.Payload{}
+	if err := protojson.Unmarshal(b, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
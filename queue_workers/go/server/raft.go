@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+const (
+	// raftApplyTimeout bounds how long a change waits to be appended to the raft log
+	raftApplyTimeout = 10 * time.Second
+	// forwardedKey marks the rpcs a follower forwarded to the leader, they are never forwarded again
+	forwardedKey = "x-raft-forwarded"
+	// forwardedForKey is the address of the client that made an rpc a follower forwarded
+	forwardedForKey = "x-raft-forwarded-for"
+	// leaderPoll is how often a follower that does not know of a leader yet looks for one again
+	leaderPoll = 10 * time.Millisecond
+)
+
+// raftLocalMethods are served by every server from its own replicas, the other rpcs
+// change the queues and are forwarded to the leader
+var raftLocalMethods = map[string]bool{
+	pb.Queue_PeekTasks_FullMethodName:       true,
+	pb.Queue_GetTask_FullMethodName:         true,
+	pb.Queue_ListDeadLetters_FullMethodName: true,
+	pb.Queue_GetDeadLetter_FullMethodName:   true,
+	pb.Queue_ListQueues_FullMethodName:      true,
+	pb.Queue_GetQueueStats_FullMethodName:   true,
+	pb.Queue_Drain_FullMethodName:           true,
+}
+
+// raftPeer is a server of the raft cluster
+type raftPeer struct {
+	id       string
+	raftAddr string
+	grpcAddr string
+}
+
+// parseRaftPeers parses the -raft-peers flag, a comma separated list of id=raft_addr/grpc_addr
+func parseRaftPeers(spec string) ([]raftPeer, error) {
+	peers := []raftPeer{}
+	for _, p := range strings.Split(spec, ",") {
+		id, addrs, ok := strings.Cut(strings.TrimSpace(p), "=")
+		raftAddr, grpcAddr, ok2 := strings.Cut(addrs, "/")
+		if !ok || !ok2 || id == "" || raftAddr == "" || grpcAddr == "" {
+			return nil, fmt.Errorf("invalid raft peer %q, expected id=raft_addr/grpc_addr", p)
+		}
+		peers = append(peers, raftPeer{id: id, raftAddr: raftAddr, grpcAddr: grpcAddr})
+	}
+	return peers, nil
+}
+
+// raftNode replicates the queues of the server to the other servers of a raft cluster. Every change
+// to a queue, and queues being created or deleted, is a raft log entry applied to the registry
+// of every server. Followers forward the rpcs making changes to the leader.
+type raftNode struct {
+	id        string
+	peers     []raftPeer
+	dir       string
+	transport raft.Transport
+	raft      *raft.Raft
+	fsm       *raftFSM
+	// addresses of the peers, the only ones trusted to tell who made the rpcs they forward
+	hosts map[string]bool
+	// connections to the servers writes were forwarded to by grpc address
+	mux   sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// raftCommand is an entry of the raft log
+type raftCommand struct {
+	Op    string `json:"op"`
+	Queue string `json:"queue"`
+	// Config of a queue created, as protojson
+	Config json.RawMessage `json:"config,omitempty"`
+	// Cmd is a change to a queue appended by its replica
+	Cmd json.RawMessage `json:"cmd,omitempty"`
+}
+
+const (
+	raftCreate = "create"
+	raftDelete = "delete"
+	raftChange = "change"
+)
+
+// newRaftNode returns the node of server id, it joins the cluster once started. The raft log
+// and snapshots are kept in dir, in memory only when empty.
+func newRaftNode(id string, peers []raftPeer, dir string, trans raft.Transport) *raftNode {
+	hosts := map[string]bool{}
+	for _, p := range peers {
+		for _, addr := range []string{p.raftAddr, p.grpcAddr} {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil || host == "" {
+				continue
+			}
+			// peers given by name forward from the addresses their name resolves to
+			addrs, err := net.LookupHost(host)
+			if err != nil {
+				log.Printf("failed to resolve raft peer %s: %s", p.id, err)
+			}
+			for _, a := range append(addrs, host) {
+				hosts[a] = true
+			}
+		}
+	}
+	return &raftNode{id: id, peers: peers, dir: dir, transport: trans, hosts: hosts, conns: map[string]*grpc.ClientConn{}}
+}
+
+// start applies the raft log to queues, the cluster is bootstrapped from the peers the first time
+func (n *raftNode) start(queues *registry) error {
+	var (
+		logs   raft.LogStore
+		stable raft.StableStore
+		snaps  raft.SnapshotStore
+	)
+	if n.dir == "" {
+		store := raft.NewInmemStore()
+		logs, stable, snaps = store, store, raft.NewInmemSnapshotStore()
+	} else {
+		if err := os.MkdirAll(n.dir, 0o755); err != nil {
+			return err
+		}
+		store, err := raftboltdb.NewBoltStore(filepath.Join(n.dir, "raft.db"))
+		if err != nil {
+			return fmt.Errorf("failed to open raft log: %w", err)
+		}
+		logs, stable = store, store
+		if snaps, err = raft.NewFileSnapshotStore(n.dir, 2, os.Stderr); err != nil {
+			return fmt.Errorf("failed to open raft snapshots: %w", err)
+		}
+	}
+	conf := raft.DefaultConfig()
+	conf.LocalID = raft.ServerID(n.id)
+	conf.LogLevel = "WARN"
+	n.fsm = &raftFSM{queues: queues}
+	r, err := raft.NewRaft(conf, n.fsm, logs, stable, snaps, n.transport)
+	if err != nil {
+		return fmt.Errorf("failed to start raft: %w", err)
+	}
+	n.raft = r
+	existing, err := raft.HasExistingState(logs, stable, snaps)
+	if err != nil {
+		return err
+	}
+	if !existing {
+		// every peer bootstraps with the same configuration, which raft allows
+		servers := []raft.Server{}
+		for _, p := range n.peers {
+			servers = append(servers, raft.Server{ID: raft.ServerID(p.id), Address: raft.ServerAddress(p.raftAddr)})
+		}
+		if err := r.BootstrapCluster(raft.Configuration{Servers: servers}).Error(); err != nil && !errors.Is(err, raft.ErrCantBootstrap) {
+			return fmt.Errorf("failed to bootstrap raft cluster: %w", err)
+		}
+	}
+	log.Printf("raft node %s started with peers %v", n.id, n.peers)
+	return nil
+}
+
+// Append commits a change made to the replica of queue name, see queue.Log
+func (n *raftNode) Append(name string, cmd []byte) (any, error) {
+	return n.apply(raftCommand{Op: raftChange, Queue: name, Cmd: cmd})
+}
+
+// apply appends c to the raft log and returns what applying it replied, it fails with UNAVAILABLE
+// unless this server leads the cluster
+func (n *raftNode) apply(c raftCommand) (any, error) {
+	if n.raft == nil {
+		return nil, status.Error(codes.Unavailable, "raft node is not started")
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	f := n.raft.Apply(b, raftApplyTimeout)
+	if err := f.Error(); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to replicate %s of queue %s: %s", c.Op, c.Queue, err)
+	}
+	if err, ok := f.Response().(error); ok {
+		return nil, err
+	}
+	return f.Response(), nil
+}
+
+func (n *raftNode) createQueue(name string, c *pb.QueueConfig) (*pb.QueueInfo, error) {
+	conf, err := protojson.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	res, err := n.apply(raftCommand{Op: raftCreate, Queue: name, Config: conf})
+	if err != nil {
+		return nil, err
+	}
+	return res.(*pb.QueueInfo), nil
+}
+
+func (n *raftNode) deleteQueue(name string) error {
+	_, err := n.apply(raftCommand{Op: raftDelete, Queue: name})
+	return err
+}
+
+func (n *raftNode) isLeader() bool {
+	return n.raft.State() == raft.Leader
+}
+
+// unaryInterceptor forwards the rpcs making changes to the leader, reads are served from
+// the replicas of this server which may lag behind the leader's
+func (n *raftNode) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if raftLocalMethods[info.FullMethod] || n.isLeader() {
+		return handler(n.forwardedPeer(ctx), req)
+	}
+	return n.forward(ctx, info.FullMethod, req)
+}
+
+// forwardedPeer puts the client that made an rpc a follower forwarded in place of the follower as
+// the peer of ctx, so that the leader rate limits the client and not every client of the follower
+// at once. Only the servers of the cluster are trusted to tell.
+func (n *raftNode) forwardedPeer(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	forwardedFor := md.Get(forwardedForKey)
+	p, ok := peer.FromContext(ctx)
+	if len(forwardedFor) != 1 || !ok {
+		return ctx
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil || !n.hosts[host] {
+		return ctx
+	}
+	return peer.NewContext(ctx, &peer.Peer{Addr: forwardedAddr(forwardedFor[0]), AuthInfo: p.AuthInfo})
+}
+
+// forwardedAddr is the address of a client as told by the follower that forwarded its rpc
+type forwardedAddr string
+
+func (a forwardedAddr) Network() string {
+	return "tcp"
+}
+
+func (a forwardedAddr) String() string {
+	return string(a)
+}
+
+func (n *raftNode) forward(ctx context.Context, method string, req any) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get(forwardedKey)) > 0 {
+		// leadership moved while the rpc was forwarded, the client retries
+		return nil, status.Error(codes.Unavailable, "not the raft leader")
+	}
+	conn, err := n.leaderConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := replyMessage(method)
+	if err != nil {
+		return nil, err
+	}
+	md = md.Copy()
+	md.Set(forwardedKey, n.id)
+	md.Delete(forwardedForKey)
+	if p, ok := peer.FromContext(ctx); ok {
+		md.Set(forwardedForKey, p.Addr.String())
+	}
+	if err := conn.Invoke(metadata.NewOutgoingContext(ctx, md), method, req, reply); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+// replyMessage returns an empty reply of method, as named by grpc
+func replyMessage(method string) (proto.Message, error) {
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok || sd.Methods().ByName(protoreflect.Name(name)) == nil {
+		return nil, status.Errorf(codes.Unimplemented, "unknown method %s", method)
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(sd.Methods().ByName(protoreflect.Name(name)).Output().FullName())
+	if err != nil {
+		return nil, err
+	}
+	return mt.New().Interface(), nil
+}
+
+// leaderConn returns a connection to the grpc server of the leader, waiting for as long as ctx
+// allows for the leader to be known as followers only hear of it a while after it is elected
+func (n *raftNode) leaderConn(ctx context.Context) (*grpc.ClientConn, error) {
+	id, addr := n.leader()
+	for addr == "" {
+		t := time.NewTimer(leaderPoll)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, status.Error(codes.Unavailable, "no raft leader is elected")
+		}
+		id, addr = n.leader()
+	}
+	n.mux.Lock()
+	defer n.mux.Unlock()
+	if conn, ok := n.conns[addr]; ok {
+		return conn, nil
+	}
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "failed to reach raft leader %s: %s", id, err)
+	}
+	n.conns[addr] = conn
+	return conn, nil
+}
+
+// leader returns the id and grpc address of the leader this server knows of, empty if none
+func (n *raftNode) leader() (raft.ServerID, string) {
+	_, id := n.raft.LeaderWithID()
+	for _, p := range n.peers {
+		if raft.ServerID(p.id) == id {
+			return id, p.grpcAddr
+		}
+	}
+	return id, ""
+}
+
+// close hands leadership over to another server and leaves the cluster
+func (n *raftNode) close() error {
+	n.mux.Lock()
+	for _, conn := range n.conns {
+		conn.Close()
+	}
+	n.conns = map[string]*grpc.ClientConn{}
+	n.mux.Unlock()
+	if n.raft == nil {
+		return nil
+	}
+	if n.isLeader() {
+		if err := n.raft.LeadershipTransfer().Error(); err != nil {
+			log.Printf("failed to transfer raft leadership: %s", err)
+		}
+	}
+	return n.raft.Shutdown().Error()
+}
+
+// raftFSM applies the raft log to the queues of the registry
+type raftFSM struct {
+	queues *registry
+}
+
+// raftQueueState is a queue in a raft snapshot, with the replicas of the queues created along
+// with it by name
+type raftQueueState struct {
+	Name     string            `json:"name"`
+	Config   json.RawMessage   `json:"config"`
+	Replicas map[string][]byte `json:"replicas"`
+}
+
+func (f *raftFSM) Apply(l *raft.Log) any {
+	var c raftCommand
+	if err := json.Unmarshal(l.Data, &c); err != nil {
+		return fmt.Errorf("invalid raft log entry %d: %w", l.Index, err)
+	}
+	switch c.Op {
+	case raftCreate:
+		conf := &pb.QueueConfig{}
+		if err := protojson.Unmarshal(c.Config, conf); err != nil {
+			return fmt.Errorf("invalid config of queue %s: %w", c.Queue, err)
+		}
+		info, err := f.queues.create(c.Queue, conf)
+		if err != nil {
+			return err
+		}
+		return info
+	case raftDelete:
+		return f.queues.delete(c.Queue)
+	case raftChange:
+		e, ok := f.queues.lookup(c.Queue)
+		if !ok {
+			return status.Errorf(codes.NotFound, fmt.Sprintf("queue %s is not present", c.Queue))
+		}
+		return e.q.(queue.Replica).Apply(c.Cmd)
+	default:
+		return fmt.Errorf("unknown raft log entry op %q", c.Op)
+	}
+}
+
+// Snapshot copies the queues right away, raft persists the copy while it applies later entries
+func (f *raftFSM) Snapshot() (raft.FSMSnapshot, error) {
+	names := []string{}
+	f.queues.each(func(name string, e *entry) {
+		if e.parent == "" && e.topic == "" {
+			names = append(names, name)
+		}
+	})
+	sort.Strings(names)
+	states := []raftQueueState{}
+	for _, name := range names {
+		e, _ := f.queues.lookup(name)
+		conf, err := protojson.Marshal(e.config)
+		if err != nil {
+			return nil, err
+		}
+		st := raftQueueState{Name: name, Config: conf, Replicas: map[string][]byte{}}
+		for _, n := range f.queues.members(name) {
+			q, ok := f.queues.get(n)
+			if !ok {
+				continue
+			}
+			if st.Replicas[n], err = snapshotReplica(q); err != nil {
+				return nil, fmt.Errorf("failed to snapshot queue %s: %w", n, err)
+			}
+		}
+		states = append(states, st)
+	}
+	data, err := json.Marshal(states)
+	if err != nil {
+		return nil, err
+	}
+	return raftSnapshot(data), nil
+}
+
+func snapshotReplica(q taskQueue) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := q.(queue.Replica).Snapshot(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Restore replaces every queue with the ones of a snapshot
+func (f *raftFSM) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	var states []raftQueueState
+	if err := json.NewDecoder(rc).Decode(&states); err != nil {
+		return fmt.Errorf("invalid raft snapshot: %w", err)
+	}
+	for _, info := range f.queues.list() {
+		// dead letter and consumer group queues go along with the queue they belong to
+		if e, ok := f.queues.lookup(info.Name); ok && e.parent == "" && e.topic == "" {
+			if err := f.queues.delete(info.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, st := range states {
+		conf := &pb.QueueConfig{}
+		if err := protojson.Unmarshal(st.Config, conf); err != nil {
+			return fmt.Errorf("invalid config of queue %s: %w", st.Name, err)
+		}
+		if _, err := f.queues.create(st.Name, conf); err != nil {
+			return err
+		}
+		for name, data := range st.Replicas {
+			q, ok := f.queues.get(name)
+			if !ok {
+				return fmt.Errorf("queue %s of snapshot is not present", name)
+			}
+			if err := q.(queue.Replica).Restore(bytes.NewReader(data)); err != nil {
+				return fmt.Errorf("failed to restore queue %s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// raftSnapshot is a copy of the queues taken by raftFSM.Snapshot
+type raftSnapshot []byte
+
+func (s raftSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s); err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s raftSnapshot) Release() {}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// clientIdle is how long the rate limiter of a client is kept after its last add
+	clientIdle = 10 * time.Minute
+	// inFlightRetryAfter is the retry hint given when a queue has too many tasks leased,
+	// when a lease is settled is up to the workers so there is nothing better to tell
+	inFlightRetryAfter = time.Second
+)
+
+// limits enforces the rate limits and the in flight cap of a queue
+type limits struct {
+	// nil when adds to the queue are not limited
+	enqueue     *rate.Limiter
+	clientRate  rate.Limit
+	clientBurst int
+	maxInFlight int
+
+	mux       sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+	// held across the in flight check and the lease so concurrent leases cannot overshoot the cap
+	leaseMux sync.Mutex
+}
+
+type clientLimiter struct {
+	*rate.Limiter
+	seen time.Time
+}
+
+func newLimits(c *pb.QueueConfig) *limits {
+	l := &limits{
+		clientRate:  rate.Limit(c.GetClientEnqueueRate()),
+		clientBurst: burst(c.GetClientEnqueueRate(), c.GetClientEnqueueBurst()),
+		maxInFlight: int(c.GetMaxInFlight()),
+		clients:     map[string]*clientLimiter{},
+	}
+	if c.GetEnqueueRate() > 0 {
+		l.enqueue = rate.NewLimiter(rate.Limit(c.GetEnqueueRate()), burst(c.GetEnqueueRate(), c.GetEnqueueBurst()))
+	}
+	return l
+}
+
+// burst defaults to a second worth of tokens
+func burst(r float64, b int32) int {
+	if b > 0 {
+		return int(b)
+	}
+	return int(math.Max(1, math.Ceil(r)))
+}
+
+// clientID tells apart the clients sharing a per client rate limit by the identity they authenticated
+// with, or by their host when the server has no acl. What clients claim to be in their metadata is
+// not trusted and the port changes with every connection. The rpcs forwarded by raft followers are
+// made by the client they forwarded them for, see raftNode.forwardedPeer.
+func clientID(ctx context.Context) string {
+	if id, ok := identityFrom(ctx); ok {
+		return "id:" + id
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
+
+// client returns the rate limiter of a client, nil when clients are not limited
+func (l *limits) client(id string, now time.Time) *rate.Limiter {
+	if l.clientRate <= 0 {
+		return nil
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	if now.Sub(l.lastSweep) > clientIdle {
+		for k, c := range l.clients {
+			if now.Sub(c.seen) > clientIdle {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+	c, ok := l.clients[id]
+	if !ok {
+		c = &clientLimiter{Limiter: rate.NewLimiter(l.clientRate, l.clientBurst)}
+		l.clients[id] = c
+	}
+	c.seen = now
+	return c.Limiter
+}
+
+// allowEnqueue takes a token for a task added by the client of ctx, a token is only taken
+// when both the queue and the client have one to spare
+func (l *limits) allowEnqueue(ctx context.Context, qid string) error {
+	now := time.Now()
+	var wait time.Duration
+	reserved := []*rate.Reservation{}
+	for _, lim := range []*rate.Limiter{l.enqueue, l.client(clientID(ctx), now)} {
+		if lim == nil {
+			continue
+		}
+		r := lim.ReserveN(now, 1)
+		reserved = append(reserved, r)
+		if d := r.DelayFrom(now); d > wait {
+			wait = d
+		}
+	}
+	if wait == 0 {
+		return nil
+	}
+	for _, r := range reserved {
+		r.CancelAt(now)
+	}
+	return exhausted(wait, "rate limit of queue %s exceeded, retry in %s", qid, wait)
+}
+
+// lease leases a task from q unless the in flight cap is reached
+func (l *limits) lease(q taskQueue, qid string, d time.Duration) (*queue.Lease[string, *pb.Payload], error) {
+	if l.maxInFlight <= 0 {
+		return leaseOf(q, d)
+	}
+	l.leaseMux.Lock()
+	defer l.leaseMux.Unlock()
+	if q.InFlight() >= l.maxInFlight {
+		return nil, exhausted(inFlightRetryAfter, "queue %s has %d tasks in flight already", qid, l.maxInFlight)
+	}
+	return leaseOf(q, d)
+}
+
+func leaseOf(q taskQueue, d time.Duration) (*queue.Lease[string, *pb.Payload], error) {
+	l, err := q.Lease(d)
+	return l, toStatus(err)
+}
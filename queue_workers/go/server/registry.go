@@ -0,0 +1,318 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultResultTTL is how long settled tasks are kept when the config of their queue does not say
+const defaultResultTTL = time.Hour
+
+var (
+	queueNameRe = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+	orders      = map[pb.QueueOrder]queue.Order{
+		pb.QueueOrder_FIFO:     queue.FIFO,
+		pb.QueueOrder_LIFO:     queue.LIFO,
+		pb.QueueOrder_PRIORITY: queue.Priority,
+	}
+	overflows = map[pb.OverflowPolicy]queue.Overflow{
+		pb.OverflowPolicy_REJECT:      queue.Reject,
+		pb.OverflowPolicy_DROP_OLDEST: queue.DropOldest,
+		pb.OverflowPolicy_BLOCK:       queue.Block,
+	}
+)
+
+// registry holds the queues served, dead letter queues are registered under their own name
+// next to the queue they belong to
+type registry struct {
+	mux     sync.RWMutex
+	queues  map[string]*entry
+	backend backend
+}
+
+type entry struct {
+	q      taskQueue
+	config *pb.QueueConfig
+	limits *limits
+	// name of the dead letter queue of q, empty when it has none
+	dlq string
+	// name of the queue q is the dead letter queue of
+	parent string
+	// consumer groups of q, tasks added to q are added to the queue of each group instead
+	groups []string
+	// name of the queue q is the queue of a consumer group of
+	topic string
+	// events of q streamed to watchers
+	events *eventLog
+}
+
+func newRegistry(b backend) *registry {
+	return &registry{queues: map[string]*entry{}, backend: b}
+}
+
+func (r *registry) get(name string) (taskQueue, bool) {
+	e, ok := r.lookup(name)
+	if !ok {
+		return nil, false
+	}
+	return e.q, true
+}
+
+func (r *registry) lookup(name string) (*entry, bool) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	e, ok := r.queues[name]
+	return e, ok
+}
+
+// each calls fn on every queue served, the registry is locked for reading meanwhile
+func (r *registry) each(fn func(name string, e *entry)) {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	for name, e := range r.queues {
+		fn(name, e)
+	}
+}
+
+// groupQueueName is the name the queue of a consumer group is registered under, it cannot clash
+// with the name of a queue created by clients
+func groupQueueName(name, group string) string {
+	return name + "@" + group
+}
+
+// reservedName reports whether name is one of those dead letter queues and the queues of consumer
+// groups are registered under, clients reach them through the queue they belong to
+func reservedName(name string) bool {
+	return strings.Contains(name, "@") || strings.HasSuffix(name, queue.DeadLetterName(""))
+}
+
+func (r *registry) info(name string, e *entry) *pb.QueueInfo {
+	return &pb.QueueInfo{Name: name, Config: e.config, DeadLetterQueue: e.dlq}
+}
+
+func (r *registry) list() []*pb.QueueInfo {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	infos := []*pb.QueueInfo{}
+	for name, e := range r.queues {
+		infos = append(infos, r.info(name, e))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// create registers a new queue, along with its dead letter queue when c.MaxAttempts is set
+func (r *registry) create(name string, c *pb.QueueConfig) (*pb.QueueInfo, error) {
+	if !queueNameRe.MatchString(name) || reservedName(name) {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("invalid queue name %q", name))
+	}
+	if c == nil {
+		c = &pb.QueueConfig{}
+	}
+	o, ok := orders[c.GetOrder()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("unknown queue order %s", c.GetOrder()))
+	}
+	overflow, ok := overflows[c.GetOverflow()]
+	if !ok {
+		return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("unknown overflow policy %s", c.GetOverflow()))
+	}
+	groups := map[string]bool{}
+	for _, g := range c.GetConsumerGroups() {
+		if !queueNameRe.MatchString(g) || reservedName(g) || groups[g] {
+			return nil, status.Errorf(codes.InvalidArgument, fmt.Sprintf("invalid or repeated consumer group %q", g))
+		}
+		groups[g] = true
+	}
+	if c.GetMaxSize() < 0 || c.GetMaxAttempts() < 0 || c.GetTtl().AsDuration() < 0 || c.GetDedupWindow().AsDuration() < 0 || c.GetResultTtl().AsDuration() < 0 || c.GetMaxInFlight() < 0 ||
+		c.GetEnqueueRate() < 0 || c.GetEnqueueBurst() < 0 || c.GetClientEnqueueRate() < 0 || c.GetClientEnqueueBurst() < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "queue config values must not be negative")
+	}
+
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.queues[name]; ok {
+		return nil, status.Errorf(codes.AlreadyExists, fmt.Sprintf("queue %s already exists", name))
+	}
+	opts := []queue.Option{queue.WithOrder(o), queue.WithMaxSize(int(c.GetMaxSize())), queue.WithOverflow(overflow)}
+	if c.GetTtl() != nil {
+		opts = append(opts, queue.WithTTL(c.GetTtl().AsDuration()))
+	}
+	if c.GetDedupWindow() != nil {
+		opts = append(opts, queue.WithDedupWindow(c.GetDedupWindow().AsDuration()))
+	}
+	resultTTL := defaultResultTTL
+	if c.GetResultTtl() != nil {
+		resultTTL = c.GetResultTtl().AsDuration()
+	}
+	// duplicates report the result of the task they were added as for as long as they are caught
+	if d := c.GetDedupWindow().AsDuration(); d > resultTTL {
+		resultTTL = d
+	}
+	opts = append(opts, queue.WithResultTTL(resultTTL))
+	// nothing is handed out from a queue with consumer groups, only from the queues of its groups
+	e, err := r.open(name, c, opts, len(groups) == 0)
+	if err != nil {
+		return nil, err
+	}
+	e.groups = c.GetConsumerGroups()
+	for _, g := range e.groups {
+		ge, err := r.open(groupQueueName(name, g), c, opts, true)
+		if err != nil {
+			r.close(name)
+			return nil, err
+		}
+		ge.topic = name
+	}
+	if err := r.backend.saveConfig(name, c); err != nil {
+		r.close(name)
+		return nil, status.Errorf(codes.Internal, fmt.Sprintf("failed to save config of queue %s: %s", name, err))
+	}
+	log.Printf("created queue %s: %v", name, c)
+	return r.info(name, e), nil
+}
+
+// open opens and registers a queue, along with its dead letter queue when deadLetter is set
+// and c.MaxAttempts is too
+func (r *registry) open(name string, c *pb.QueueConfig, opts []queue.Option, deadLetter bool) (*entry, error) {
+	e := &entry{config: c, limits: newLimits(c)}
+	if deadLetter && c.GetMaxAttempts() > 0 {
+		dlqName := queue.DeadLetterName(name)
+		dlq, err := r.backend.open(dlqName)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, fmt.Sprintf("failed to open queue %s: %s", dlqName, err))
+		}
+		r.queues[dlqName] = &entry{q: dlq, config: &pb.QueueConfig{}, limits: newLimits(&pb.QueueConfig{}), parent: name, events: newEventLog(dlq, eventLogSize)}
+		e.dlq = dlqName
+		opts = append(opts, queue.WithDeadLetter(dlq, int(c.GetMaxAttempts())))
+	}
+	q, err := r.backend.open(name, opts...)
+	if err != nil {
+		if e.dlq != "" {
+			r.close(e.dlq)
+		}
+		return nil, status.Errorf(codes.Internal, fmt.Sprintf("failed to open queue %s: %s", name, err))
+	}
+	e.q = q
+	e.events = newEventLog(q, eventLogSize)
+	r.queues[name] = e
+	return e, nil
+}
+
+// close unregisters and closes a queue along with the queues created with it, keeping whatever
+// is persisted around as a failed create must not lose tasks
+func (r *registry) close(name string) {
+	for _, n := range r.family(name) {
+		r.queues[n].q.Close()
+		delete(r.queues, n)
+	}
+}
+
+// members returns the names of a registered queue and of the queues created along with it
+func (r *registry) members(name string) []string {
+	r.mux.RLock()
+	defer r.mux.RUnlock()
+	return r.family(name)
+}
+
+// family returns the names of a registered queue and of the queues created along with it,
+// those of its consumer groups and their dead letter queues. It is called with the lock held.
+func (r *registry) family(name string) []string {
+	e, ok := r.queues[name]
+	if !ok {
+		return nil
+	}
+	names := []string{name}
+	if e.dlq != "" {
+		names = append(names, e.dlq)
+	}
+	for _, g := range e.groups {
+		names = append(names, r.family(groupQueueName(name, g))...)
+	}
+	return names
+}
+
+// delete closes a queue and its dead letter queue, dropping every task in them
+func (r *registry) delete(name string) error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	e, ok := r.queues[name]
+	if !ok {
+		return status.Errorf(codes.NotFound, fmt.Sprintf("queue %s is not present", name))
+	}
+	if e.parent != "" {
+		return status.Errorf(codes.FailedPrecondition, fmt.Sprintf("queue %s is the dead letter queue of %s, delete that instead", name, e.parent))
+	}
+	if e.topic != "" {
+		return status.Errorf(codes.FailedPrecondition, fmt.Sprintf("queue %s is the queue of a consumer group of %s, delete that instead", name, e.topic))
+	}
+	var errs []error
+	for _, n := range r.family(name) {
+		if err := r.drop(n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return status.Errorf(codes.Internal, fmt.Sprintf("failed to delete queue %s: %s", name, err))
+	}
+	log.Printf("deleted queue %s", name)
+	return nil
+}
+
+// drop unregisters and closes a queue, removing its data
+func (r *registry) drop(name string) error {
+	e, ok := r.queues[name]
+	if !ok {
+		return nil
+	}
+	delete(r.queues, name)
+	err := e.q.Close()
+	if derr := r.backend.drop(name); err == nil {
+		err = derr
+	}
+	return err
+}
+
+// restore reopens the queues saved by the backend
+func (r *registry) restore() error {
+	configs, err := r.backend.configs()
+	if err != nil {
+		return err
+	}
+	names := []string{}
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := r.create(name, configs[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// closeAll closes every queue, flushing persisted ones
+func (r *registry) closeAll() error {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	var errs []error
+	for name, e := range r.queues {
+		if err := e.q.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close queue %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
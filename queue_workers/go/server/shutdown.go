@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "queue-workers/proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errDraining is returned by adds and watches once the server is draining,
+// UNAVAILABLE so clients retry against another server
+var errDraining = status.Error(codes.Unavailable, "server is draining")
+
+func (s *server) Drain(ctx context.Context, r *pb.DrainRequest) (*pb.DrainReply, error) {
+	s.drain()
+	reply := &pb.DrainReply{}
+	s.queues.each(func(name string, e *entry) {
+		st := e.q.Stats()
+		reply.Depth += int64(st.Depth + st.Delayed)
+		reply.InFlight += int64(st.InFlight)
+	})
+	return reply, nil
+}
+
+// drain stops taking new tasks and ends the watches, it is safe to call more than once
+func (s *server) drain() {
+	s.drainOnce.Do(func() {
+		log.Print("draining, new tasks are refused")
+		close(s.draining)
+	})
+}
+
+func (s *server) isDraining() bool {
+	select {
+	case <-s.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// shutdown drains the server, waits up to timeout for the rpcs in progress to finish
+// and closes the queues so the persisted ones are flushed
+func (s *server) shutdown(grpcServ *grpc.Server, timeout time.Duration) error {
+	s.drain()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServ.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		log.Printf("rpcs still running after %s, cancelling them", timeout)
+		grpcServ.Stop()
+		<-stopped
+	}
+	if s.cluster != nil {
+		// stop applying the raft log before the queues it is applied to are closed
+		if err := s.cluster.close(); err != nil {
+			log.Printf("failed to leave raft cluster: %s", err)
+		}
+	}
+	if _, ok := s.queues.backend.(memoryBackend); ok {
+		lost := 0
+		s.queues.each(func(name string, e *entry) {
+			st := e.q.Stats()
+			lost += st.Depth + st.Delayed + st.InFlight
+		})
+		if lost > 0 {
+			log.Printf("queues are not persisted, %d tasks are lost", lost)
+		}
+	}
+	return s.queues.closeAll()
+}
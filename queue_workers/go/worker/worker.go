@@ -0,0 +1,341 @@
+// Package worker runs handlers on the tasks of a queue served over gRPC.
+//
+// A Worker leases tasks one at a time up to its concurrency limit, hands each one
+// to the handler registered for its name and acks it once handled, or nacks it
+// when the handler keeps failing so the server redelivers or dead letters it.
+// New tasks are picked up as soon as they are added by watching the queue.
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	pb "queue-workers/proto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrNoHandler is reported for tasks whose name matches no registered handler
+var ErrNoHandler = errors.New("no handler registered for task")
+
+// Handler processes a task, a returned error fails the attempt. The result is handed back to
+// producers looking the task up, it can be nil. ctx is done when the lease on the task runs out,
+// at which point the server hands the task to another worker.
+type Handler func(ctx context.Context, t *pb.Task) ([]byte, error)
+
+// settleTimeout bounds the ack or nack of a handled task
+const settleTimeout = 10 * time.Second
+
+// Backoff is an exponential delay between retries, doubling from Initial up to Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+func (b Backoff) delay(retry int) time.Duration {
+	d := b.Initial
+	for i := 0; i < retry && d < b.Max; i++ {
+		d *= 2
+	}
+	if d > b.Max {
+		d = b.Max
+	}
+	return d
+}
+
+// Option configures a Worker created by New
+type Option func(*Worker)
+
+// WithConcurrency sets how many tasks are handled at once, defaults to 1
+func WithConcurrency(n int) Option {
+	return func(w *Worker) {
+		w.concurrency = n
+	}
+}
+
+// WithLeaseDuration sets how long a task is hidden from other workers while it is handled, defaults to 30s
+func WithLeaseDuration(d time.Duration) Option {
+	return func(w *Worker) {
+		w.leaseDuration = d
+	}
+}
+
+// WithRetries retries a failing handler up to n times while the task is leased before nacking it,
+// waiting b between attempts. b is also used to back off from failing lease and watch calls.
+func WithRetries(n int, b Backoff) Option {
+	return func(w *Worker) {
+		w.retries = n
+		w.backoff = b
+	}
+}
+
+// WithPollInterval sets how often an empty queue is polled in case the watch misses a task, defaults to 5s
+func WithPollInterval(d time.Duration) Option {
+	return func(w *Worker) {
+		w.pollInterval = d
+	}
+}
+
+// WithGroup takes tasks from the consumer group named g, required for queues with consumer groups.
+// Leases taken from a group are acked and nacked within it.
+func WithGroup(g string) Option {
+	return func(w *Worker) {
+		w.group = g
+	}
+}
+
+// Worker handles the tasks of a single queue
+type Worker struct {
+	cli           pb.QueueClient
+	queue         string
+	group         string
+	mux           sync.RWMutex
+	handlers      map[string]Handler
+	concurrency   int
+	leaseDuration time.Duration
+	retries       int
+	backoff       Backoff
+	pollInterval  time.Duration
+}
+
+func New(cli pb.QueueClient, queue string, opts ...Option) *Worker {
+	w := &Worker{
+		cli:           cli,
+		queue:         queue,
+		handlers:      map[string]Handler{},
+		concurrency:   1,
+		leaseDuration: 30 * time.Second,
+		retries:       2,
+		backoff:       Backoff{Initial: 100 * time.Millisecond, Max: 5 * time.Second},
+		pollInterval:  5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if w.concurrency < 1 {
+		w.concurrency = 1
+	}
+	return w
+}
+
+// Handle registers h for the tasks named name, or named name followed by a colon and an id,
+// so "email" handles both "email" and "email:42". Exact names win over prefixes.
+func (w *Worker) Handle(name string, h Handler) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	w.handlers[name] = h
+}
+
+func (w *Worker) handler(name string) (Handler, bool) {
+	w.mux.RLock()
+	defer w.mux.RUnlock()
+	if h, ok := w.handlers[name]; ok {
+		return h, true
+	}
+	prefix, _, ok := strings.Cut(name, ":")
+	if !ok {
+		return nil, false
+	}
+	h, ok := w.handlers[prefix]
+	return h, ok
+}
+
+// Run leases and handles tasks until ctx is done. No new task is leased after that,
+// the ones being handled are finished and settled before Run returns. Run also stops when
+// the server refuses a lease for a reason retrying cannot fix, such as a missing group,
+// in which case it returns the error of the lease.
+func (w *Worker) Run(ctx context.Context) error {
+	// stops the watch when leases are refused, handlers are only cut short by their lease
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	wake := make(chan struct{}, 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.watch(ctx, wake)
+	}()
+
+	slots := make(chan struct{}, w.concurrency)
+	failures := 0
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			log.Printf("worker on queue %s stopping, waiting for %d tasks", w.queue, len(slots))
+			wg.Wait()
+			return nil
+		}
+		r, err := w.cli.LeaseTask(ctx, &pb.TaskLeaseRequest{Queue: w.queue, Group: w.group, LeaseDuration: durationpb.New(w.leaseDuration)})
+		if err == nil && r.GetTask() != nil {
+			failures = 0
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-slots }()
+				w.process(ctx, r)
+			}()
+			continue
+		}
+		<-slots
+		switch {
+		case ctx.Err() != nil:
+			// stopping, picked up by the next iteration
+		case err != nil && !retryable(err):
+			log.Printf("worker on queue %s stopping, leases are refused: %s", w.queue, err)
+			stop()
+			wg.Wait()
+			return fmt.Errorf("failed to lease task from queue %s: %w", w.queue, err)
+		case err != nil:
+			d, ok := retryDelay(err)
+			if !ok {
+				d = w.backoff.delay(failures)
+				failures++
+			}
+			log.Printf("failed to lease task from queue %s, retrying in %s: %s", w.queue, d, err)
+			sleep(ctx, d)
+		default:
+			// the queue is empty, wait for the watch to report a new task
+			failures = 0
+			t := time.NewTimer(w.pollInterval)
+			select {
+			case <-wake:
+			case <-t.C:
+			case <-ctx.Done():
+			}
+			t.Stop()
+		}
+	}
+}
+
+// watch nudges wake whenever a task becomes available, reconnecting until ctx is done
+func (w *Worker) watch(ctx context.Context, wake chan<- struct{}) {
+	for failures := 0; ; failures++ {
+		s, err := w.cli.WatchQueue(ctx, &pb.TaskWatchRequest{Queue: w.queue, Group: w.group})
+		if err == nil {
+			// tasks added while the watch was down are only seen by leasing again
+			notify(wake)
+			for {
+				if _, err = s.Recv(); err != nil {
+					break
+				}
+				failures = 0
+				notify(wake)
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if !retryable(err) {
+			// new tasks are still picked up by polling
+			log.Printf("watch on queue %s refused, not retrying: %s", w.queue, err)
+			return
+		}
+		log.Printf("watch on queue %s failed: %s", w.queue, err)
+		sleep(ctx, w.backoff.delay(failures))
+	}
+}
+
+// retryable reports whether a call failing with err may succeed when made again, calls refused
+// for what they ask for or who makes them fail the same way every time
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition, codes.PermissionDenied,
+		codes.Unauthenticated, codes.Unimplemented:
+		return false
+	}
+	return true
+}
+
+// retryDelay is how long the server asked to wait before trying again, when it did
+func retryDelay(err error) (time.Duration, bool) {
+	for _, d := range status.Convert(err).Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return ri.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
+
+func notify(c chan<- struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
+
+// process handles a leased task and settles the lease with the outcome
+func (w *Worker) process(ctx context.Context, r *pb.TaskLeaseReply) {
+	t := r.GetTask()
+	// handlers are let finish on shutdown, they are only cut short when the lease runs out
+	hctx, cancel := context.WithDeadline(context.WithoutCancel(ctx), r.GetExpiresAt().AsTime())
+	result, err := w.handle(hctx, ctx.Done(), t)
+	cancel()
+
+	sctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), settleTimeout)
+	defer cancel()
+	if err == nil {
+		if _, err := w.cli.AckTask(sctx, &pb.TaskAckRequest{LeaseId: r.GetLeaseId(), Result: result}); err != nil {
+			log.Printf("failed to ack task %s: %s", t.GetName(), err)
+		}
+		return
+	}
+	log.Printf("task %s failed on attempt %d: %s", t.GetName(), t.GetAttempts(), err)
+	if _, err := w.cli.NackTask(sctx, &pb.TaskNackRequest{LeaseId: r.GetLeaseId(), Error: err.Error()}); err != nil {
+		log.Printf("failed to nack task %s: %s", t.GetName(), err)
+	}
+}
+
+// handle runs the handler of t, retrying it with backoff until it succeeds, runs out of retries,
+// the lease runs out or the worker is stopping
+func (w *Worker) handle(ctx context.Context, stopping <-chan struct{}, t *pb.Task) ([]byte, error) {
+	h, ok := w.handler(t.GetName())
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", t.GetName(), ErrNoHandler)
+	}
+	for retry := 0; ; retry++ {
+		result, err := call(ctx, h, t)
+		if err == nil || retry >= w.retries {
+			return result, err
+		}
+		select {
+		case <-stopping:
+			// leave the retries to another worker
+			return nil, err
+		default:
+		}
+		if !sleep(ctx, w.backoff.delay(retry)) {
+			return nil, err
+		}
+	}
+}
+
+// call runs h, turning a panic into an error so a bad task does not take the worker down
+func call(ctx context.Context, h Handler, t *pb.Task) (result []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, t)
+}
+
+// sleep waits for d, returning false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,250 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	pb "queue-workers/proto"
+	"queue-workers/queue"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeServer serves the lease and watch RPCs of a single queue, or of its single consumer group
+type fakeServer struct {
+	pb.UnimplementedQueueServer
+	q      queue.Queue[string, *pb.Task]
+	group  string
+	mux    sync.Mutex
+	acked  []string
+	nacked []string
+}
+
+func (s *fakeServer) checkGroup(g string) error {
+	if g != s.group {
+		return status.Errorf(codes.InvalidArgument, "unknown consumer group %q", g)
+	}
+	return nil
+}
+
+func (s *fakeServer) LeaseTask(ctx context.Context, r *pb.TaskLeaseRequest) (*pb.TaskLeaseReply, error) {
+	if err := s.checkGroup(r.GetGroup()); err != nil {
+		return nil, err
+	}
+	l, err := s.q.Lease(r.GetLeaseDuration().AsDuration())
+	if err != nil {
+		return nil, err
+	}
+	if l == nil {
+		return &pb.TaskLeaseReply{}, nil
+	}
+	_, t := l.Item().KeyValue()
+	return &pb.TaskLeaseReply{
+		Task:      &pb.Task{Name: t.GetName(), Payload: t.GetPayload(), Attempts: int32(l.Item().Attempts())},
+		LeaseId:   l.ID() + "/" + t.GetName(),
+		ExpiresAt: timestamppb.New(l.ExpiresAt()),
+	}, nil
+}
+
+func (s *fakeServer) settle(leaseID string, fn func(string, ...queue.SettleOption) error, opt queue.SettleOption, into *[]string) error {
+	for i := len(leaseID) - 1; i >= 0; i-- {
+		if leaseID[i] == '/' {
+			if err := fn(leaseID[:i], opt); err != nil {
+				return err
+			}
+			s.mux.Lock()
+			*into = append(*into, leaseID[i+1:])
+			s.mux.Unlock()
+			return nil
+		}
+	}
+	return errors.New("malformed lease id")
+}
+
+func (s *fakeServer) AckTask(ctx context.Context, r *pb.TaskAckRequest) (*pb.TaskAckReply, error) {
+	return &pb.TaskAckReply{}, s.settle(r.GetLeaseId(), s.q.Ack, queue.WithResult(r.GetResult()), &s.acked)
+}
+
+func (s *fakeServer) NackTask(ctx context.Context, r *pb.TaskNackRequest) (*pb.TaskNackReply, error) {
+	return &pb.TaskNackReply{}, s.settle(r.GetLeaseId(), s.q.Nack, queue.WithError(r.GetError()), &s.nacked)
+}
+
+func (s *fakeServer) WatchQueue(r *pb.TaskWatchRequest, w pb.Queue_WatchQueueServer) error {
+	if err := s.checkGroup(r.GetGroup()); err != nil {
+		return err
+	}
+	sub := s.q.Subscribe()
+	defer sub.Close()
+	if err := w.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case e := <-sub.C():
+			if e.Type != queue.EventAdded && e.Type != queue.EventRequeued {
+				continue
+			}
+			_, t := e.Item.KeyValue()
+			if err := w.Send(&pb.TaskWatchReply{Task: t}); err != nil {
+				return err
+			}
+		case <-w.Context().Done():
+			return nil
+		}
+	}
+}
+
+func (s *fakeServer) add(t *testing.T, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		require.NoError(t, s.q.Add(name, &pb.Task{Name: name}))
+	}
+}
+
+func (s *fakeServer) settled() ([]string, []string) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return append([]string{}, s.acked...), append([]string{}, s.nacked...)
+}
+
+func startFake(t *testing.T) (*fakeServer, pb.QueueClient) {
+	t.Helper()
+	fake := &fakeServer{q: queue.NewQueue[string, *pb.Task](queue.WithResultTTL(time.Minute))}
+	lis := bufconn.Listen(1 << 20)
+	grpcServ := grpc.NewServer()
+	pb.RegisterQueueServer(grpcServ, fake)
+	go grpcServ.Serve(lis)
+	t.Cleanup(grpcServ.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return fake, pb.NewQueueClient(conn)
+}
+
+func TestWorker(t *testing.T) {
+	fake, cli := startFake(t)
+	fake.add(t, "echo:1", "unknown")
+	w := New(cli, "q1", WithConcurrency(2), WithRetries(1, Backoff{Initial: time.Millisecond, Max: time.Millisecond}))
+	var mux sync.Mutex
+	calls := map[string]int{}
+	w.Handle("echo", func(ctx context.Context, t *pb.Task) ([]byte, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		calls[t.GetName()]++
+		return []byte(t.GetName()), nil
+	})
+	w.Handle("flaky", func(ctx context.Context, t *pb.Task) ([]byte, error) {
+		mux.Lock()
+		defer mux.Unlock()
+		calls[t.GetName()]++
+		if calls[t.GetName()] == 1 {
+			return nil, errors.New("try again")
+		}
+		return nil, nil
+	})
+	w.Handle("broken", func(ctx context.Context, t *pb.Task) ([]byte, error) {
+		mux.Lock()
+		calls[t.GetName()]++
+		mux.Unlock()
+		panic("boom")
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	// added after the worker went idle, picked up through the watch
+	time.Sleep(50 * time.Millisecond)
+	fake.add(t, "echo:2", "flaky", "broken")
+
+	assert.Eventually(t, func() bool {
+		acked, nacked := fake.settled()
+		return len(acked)+len(nacked) >= 6
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	acked, nacked := fake.settled()
+	assert.ElementsMatch(t, acked, []string{"echo:1", "echo:2", "flaky"})
+	// unknown is nacked on every delivery, broken after its retry
+	assert.Contains(t, nacked, "unknown")
+	assert.Contains(t, nacked, "broken")
+	st, _ := fake.q.Status("echo:2")
+	assert.Equal(t, st.Result, []byte("echo:2"))
+	st, _ = fake.q.Status("broken")
+	assert.Contains(t, st.LastError, "boom")
+	mux.Lock()
+	defer mux.Unlock()
+	assert.Equal(t, calls["flaky"], 2)
+	assert.GreaterOrEqual(t, calls["broken"], 2)
+}
+
+func TestWorkerGracefulShutdown(t *testing.T) {
+	fake, cli := startFake(t)
+	fake.add(t, "slow")
+	w := New(cli, "q1")
+	started, release := make(chan struct{}), make(chan struct{})
+	w.Handle("slow", func(ctx context.Context, t *pb.Task) ([]byte, error) {
+		close(started)
+		<-release
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	<-started
+	cancel()
+	select {
+	case <-done:
+		t.Fatal("Run returned before the task being handled finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-done)
+	acked, _ := fake.settled()
+	assert.Equal(t, acked, []string{"slow"})
+}
+
+func TestWorkerGroup(t *testing.T) {
+	fake, cli := startFake(t)
+	fake.group = "billing"
+	fake.add(t, "echo")
+	echo := func(ctx context.Context, t *pb.Task) ([]byte, error) { return nil, nil }
+
+	// leases without the group are refused for good, Run gives up instead of retrying
+	w := New(cli, "q1")
+	w.Handle("echo", echo)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	err := w.Run(ctx)
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	assert.NoError(t, ctx.Err())
+
+	w = New(cli, "q1", WithGroup("billing"))
+	w.Handle("echo", echo)
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.Run(ctx) }()
+	assert.Eventually(t, func() bool {
+		acked, _ := fake.settled()
+		return len(acked) == 1
+	}, 2*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+}